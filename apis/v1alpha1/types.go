@@ -13,13 +13,200 @@ type ProviderConfigStatus struct {
 
 // ProviderCredentials required to authenticate.
 type ProviderCredentials struct {
-	// Source of the provider credentials.
+	// Source of the provider credentials. The Slicer API token can be read
+	// from a Secret, an environment variable of the provider, or a file
+	// mounted into the provider.
 	// +kubebuilder:validation:Enum=None;Secret;InjectedIdentity;Environment;Filesystem
 	Source xpv1.CredentialsSource `json:"source"`
 
 	xpv1.CommonCredentialSelectors `json:",inline"`
 }
 
+// TLSOptions configures how the TLS certificate of the Slicer API endpoint is
+// verified.
+type TLSOptions struct {
+	// CABundle is a PEM encoded bundle of CA certificates trusted to sign the
+	// Slicer API's certificate, in addition to the system's.
+	// +optional
+	CABundle string `json:"caBundle,omitempty"`
+
+	// CABundleSecretRef selects a secret key holding a PEM encoded bundle of
+	// CA certificates, trusted in addition to CABundle. The secret of a
+	// namespaced ProviderConfig is always read from the ProviderConfig's
+	// namespace, whatever namespace is selected.
+	// +optional
+	CABundleSecretRef *xpv1.SecretKeySelector `json:"caBundleSecretRef,omitempty"`
+
+	// InsecureSkipVerify disables verification of the Slicer API's
+	// certificate, exposing the API token to anyone able to intercept
+	// connections. Only use it for testing.
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// MetadataTagOptions configures the tags derived from the metadata of VM
+// resources that are added to the VMs they create, so that VMs can be traced
+// back to their resources. VMs are always tagged with k8s-name=<name> and
+// k8s-namespace=<namespace>.
+type MetadataTagOptions struct {
+	// Labels maps label keys of VM resources to tag keys. A VM resource with
+	// one of the labels is tagged with <tag key>=<label value>.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations maps annotation keys of VM resources to tag keys. A VM
+	// resource with one of the annotations is tagged with
+	// <tag key>=<annotation value>.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ConnectionDetailsOptions configures the optional connection details that
+// are published for VMs, in addition to their hostname and IP.
+type ConnectionDetailsOptions struct {
+	// IncludeTags publishes the VM's observed tags as a JSON array under the
+	// "tags" key.
+	// +optional
+	IncludeTags bool `json:"includeTags,omitempty"`
+
+	// SSHConfig publishes a ready-to-use SSH config block for the VM under
+	// the "ssh_config" key.
+	// +optional
+	SSHConfig bool `json:"sshConfig,omitempty"`
+
+	// SSHUser is the SSH user published in the username and endpoint
+	// connection details, and in the SSH config block.
+	// +kubebuilder:default="ubuntu"
+	// +optional
+	SSHUser string `json:"sshUser,omitempty"`
+
+	// SSHPort is the SSH port published in the port and endpoint connection
+	// details, and in the SSH config block.
+	// +kubebuilder:default=22
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	SSHPort int `json:"sshPort,omitempty"`
+}
+
+// RightsizingOptions configures sizing recommendations for VMs, based on their
+// 15 minute load average per CPU and their memory usage.
+type RightsizingOptions struct {
+	// LowUtilizationPercent is the utilization below which both CPU and
+	// memory must be for a VM to be recommended a smaller size.
+	// +kubebuilder:default=20
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	LowUtilizationPercent int `json:"lowUtilizationPercent,omitempty"`
+
+	// HighUtilizationPercent is the utilization above which either CPU or
+	// memory must be for a VM to be recommended a larger size.
+	// +kubebuilder:default=80
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	HighUtilizationPercent int `json:"highUtilizationPercent,omitempty"`
+}
+
+// OperationTimeouts configures how long individual Slicer API calls may take.
+type OperationTimeouts struct {
+	// Create is the timeout for creating a VM. Defaults to 45s.
+	// +optional
+	Create *metav1.Duration `json:"create,omitempty"`
+
+	// Delete is the timeout for deleting a VM. Defaults to 30s.
+	// +optional
+	Delete *metav1.Duration `json:"delete,omitempty"`
+
+	// Observe is the timeout for listing the VMs of a host group, and for
+	// reading the stats of a VM. Defaults to 15s.
+	// +optional
+	Observe *metav1.Duration `json:"observe,omitempty"`
+
+	// Update is the timeout for updating a VM in place, for example asking it
+	// to reboot. Defaults to 30s.
+	// +optional
+	Update *metav1.Duration `json:"update,omitempty"`
+
+	// Shutdown is how long to wait for a VM with shutdownBeforeDelete to shut
+	// down before it is deleted anyway. Defaults to 1m.
+	// +optional
+	Shutdown *metav1.Duration `json:"shutdown,omitempty"`
+
+	// CloudInit is how long after a VM with waitForCloudInit was created
+	// cloud-init may take to finish before the VM is reported as unavailable.
+	// Defaults to 10m.
+	// +optional
+	CloudInit *metav1.Duration `json:"cloudInit,omitempty"`
+
+	// Request is the timeout for each individual HTTP request to the Slicer
+	// API, including retries of failed requests. Unlimited by default.
+	// +optional
+	Request *metav1.Duration `json:"request,omitempty"`
+}
+
+// RestartDetectionOptions configures when VMs are considered to be restarting
+// frequently.
+type RestartDetectionOptions struct {
+	// Threshold is the number of restarts within the window above which a VM
+	// is considered to be restarting frequently. Defaults to 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Threshold int `json:"threshold,omitempty"`
+
+	// Window is the period over which restarts are counted. Defaults to 1h.
+	// +optional
+	Window *metav1.Duration `json:"window,omitempty"`
+}
+
+// An UpdatePolicy determines what happens when VM parameters that can only be
+// set on creation are changed.
+type UpdatePolicy string
+
+// Update policies.
+const (
+	// UpdatePolicyWarn reports that the VM must be recreated for the change
+	// to take effect, using a warning event and a RecreateRequired condition.
+	UpdatePolicyWarn UpdatePolicy = "Warn"
+
+	// UpdatePolicyRecreate deletes the VM so that it is recreated with the
+	// changed parameters.
+	UpdatePolicyRecreate UpdatePolicy = "Recreate"
+)
+
+// A HostGroupSelectionStrategy determines which of a set of candidate host
+// groups VMs are created in.
+type HostGroupSelectionStrategy string
+
+// Host group selection strategies.
+const (
+	// HostGroupSelectionLeastLoaded selects the candidate host group with
+	// the fewest VMs.
+	HostGroupSelectionLeastLoaded HostGroupSelectionStrategy = "LeastLoaded"
+)
+
+// HostGroupSelection configures how the host group of VMs that do not specify
+// one is selected.
+type HostGroupSelection struct {
+	// Strategy determines which of the candidate host groups is selected.
+	// +kubebuilder:validation:Enum=LeastLoaded
+	// +kubebuilder:default=LeastLoaded
+	// +optional
+	Strategy HostGroupSelectionStrategy `json:"strategy,omitempty"`
+
+	// HostGroups are the candidate host groups. Ties are broken by their
+	// order.
+	// +kubebuilder:validation:MinItems=1
+	HostGroups []string `json:"hostGroups"`
+
+	// MaxVMs is the number of VMs at which a host group is full, and no
+	// longer selected. Unlimited if not specified.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxVMs int `json:"maxVms,omitempty"`
+}
+
 type ProviderConfigSpec struct {
 	// Credentials required to authenticate to this provider.
 	// The credentials should contain a "token" key with the Slicer API token.
@@ -30,10 +217,111 @@ type ProviderConfigSpec struct {
 	// +optional
 	URL string `json:"url,omitempty"`
 
+	// AllowedEndpointOverrides are the Slicer API endpoint URLs VMs using
+	// this config may select with their endpointOverride, instead of URL.
+	// The config's credentials are sent to the selected endpoint, so only
+	// endpoints trusted with them should be listed.
+	// +optional
+	AllowedEndpointOverrides []string `json:"allowedEndpointOverrides,omitempty"`
+
+	// TLS configures how the TLS certificate of the Slicer API endpoint is
+	// verified. By default it must be signed by a CA the system trusts.
+	// +optional
+	TLS *TLSOptions `json:"tls,omitempty"`
+
 	// HostGroup is the default host group for VM operations.
 	// +kubebuilder:default="api"
 	// +optional
 	HostGroup string `json:"hostGroup,omitempty"`
+
+	// HostGroupSelection selects the host group of VMs that do not specify
+	// one from a set of candidates, instead of using HostGroup. The selected
+	// host group is recorded in the VM's status and kept from then on.
+	// +optional
+	HostGroupSelection *HostGroupSelection `json:"hostGroupSelection,omitempty"`
+
+	// DefaultCPUs is the number of CPUs of VMs that do not specify one.
+	// Defaults to 2.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	DefaultCPUs int `json:"defaultCpus,omitempty"`
+
+	// DefaultRAMGB is the RAM in GB of VMs that do not specify it. Defaults
+	// to 4.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	DefaultRAMGB int `json:"defaultRamGb,omitempty"`
+
+	// MaxCPUs is the largest number of CPUs a single VM may request, usually
+	// the CPU count of a host in the host group. VMs requesting more are
+	// rejected before they are created. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxCPUs int `json:"maxCpus,omitempty"`
+
+	// MaxRAMGB is the largest amount of RAM in GB a single VM may request,
+	// usually the RAM of a host in the host group. VMs requesting more are
+	// rejected before they are created. Zero means no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxRAMGB int `json:"maxRamGb,omitempty"`
+
+	// MaxConcurrentCreates is how many VMs the provider creates at once in
+	// each host group. Further creates wait until one finishes. Zero means
+	// no limit.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentCreates int `json:"maxConcurrentCreates,omitempty"`
+
+	// ConnectionDetails configures the optional connection details published
+	// for VMs.
+	// +optional
+	ConnectionDetails ConnectionDetailsOptions `json:"connectionDetails,omitempty"`
+
+	// MetadataTags tags VMs with the metadata of their VM resources when they
+	// are created. Tags the VM resource sets itself take precedence.
+	// +optional
+	MetadataTags *MetadataTagOptions `json:"metadataTags,omitempty"`
+
+	// UpdatePolicy determines what happens when VM parameters that can only
+	// be set on creation are changed. Warn reports that the VM must be
+	// recreated, Recreate deletes the VM so it is recreated.
+	// +kubebuilder:validation:Enum=Warn;Recreate
+	// +kubebuilder:default=Warn
+	// +optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// ConnectionSecretNamespace is a namespace that connection details of VMs
+	// using this config are additionally published to, for cluster-scoped
+	// consumers. Each VM's details are written to a secret named
+	// "<vm-namespace>-<connection-secret-name>". It is only honoured for
+	// ClusterProviderConfigs, since it lets VMs in any namespace write to it.
+	// +optional
+	ConnectionSecretNamespace string `json:"connectionSecretNamespace,omitempty"`
+
+	// Rightsizing enables informational sizing recommendations for VMs,
+	// reported using a ResizeRecommended condition.
+	// +optional
+	Rightsizing *RightsizingOptions `json:"rightsizing,omitempty"`
+
+	// Timeouts configures how long individual Slicer API calls may take.
+	// Each reconcile as a whole is still bounded by the provider's reconcile
+	// timeout.
+	// +optional
+	Timeouts OperationTimeouts `json:"timeouts,omitempty"`
+
+	// MaxRetries is how many times a Slicer API read that failed with a
+	// server error or a connection error is retried. Writes are never
+	// retried. Defaults to 0.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	MaxRetries int `json:"maxRetries,omitempty"`
+
+	// RestartDetection configures when VMs are reported as restarting
+	// frequently, using a FrequentRestarts condition.
+	// +optional
+	RestartDetection RestartDetectionOptions `json:"restartDetection,omitempty"`
 }
 
 // +kubebuilder:object:root=true
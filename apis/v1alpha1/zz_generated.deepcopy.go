@@ -9,6 +9,8 @@
 package v1alpha1
 
 import (
+	"github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -129,6 +131,120 @@ func (in *ClusterProviderConfigUsageList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConnectionDetailsOptions) DeepCopyInto(out *ConnectionDetailsOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConnectionDetailsOptions.
+func (in *ConnectionDetailsOptions) DeepCopy() *ConnectionDetailsOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(ConnectionDetailsOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostGroupSelection) DeepCopyInto(out *HostGroupSelection) {
+	*out = *in
+	if in.HostGroups != nil {
+		in, out := &in.HostGroups, &out.HostGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostGroupSelection.
+func (in *HostGroupSelection) DeepCopy() *HostGroupSelection {
+	if in == nil {
+		return nil
+	}
+	out := new(HostGroupSelection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetadataTagOptions) DeepCopyInto(out *MetadataTagOptions) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetadataTagOptions.
+func (in *MetadataTagOptions) DeepCopy() *MetadataTagOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(MetadataTagOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperationTimeouts) DeepCopyInto(out *OperationTimeouts) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Delete != nil {
+		in, out := &in.Delete, &out.Delete
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Observe != nil {
+		in, out := &in.Observe, &out.Observe
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Update != nil {
+		in, out := &in.Update, &out.Update
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Shutdown != nil {
+		in, out := &in.Shutdown, &out.Shutdown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.CloudInit != nil {
+		in, out := &in.CloudInit, &out.CloudInit
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Request != nil {
+		in, out := &in.Request, &out.Request
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperationTimeouts.
+func (in *OperationTimeouts) DeepCopy() *OperationTimeouts {
+	if in == nil {
+		return nil
+	}
+	out := new(OperationTimeouts)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProviderConfig) DeepCopyInto(out *ProviderConfig) {
 	*out = *in
@@ -192,6 +308,34 @@ func (in *ProviderConfigList) DeepCopyObject() runtime.Object {
 func (in *ProviderConfigSpec) DeepCopyInto(out *ProviderConfigSpec) {
 	*out = *in
 	in.Credentials.DeepCopyInto(&out.Credentials)
+	if in.AllowedEndpointOverrides != nil {
+		in, out := &in.AllowedEndpointOverrides, &out.AllowedEndpointOverrides
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostGroupSelection != nil {
+		in, out := &in.HostGroupSelection, &out.HostGroupSelection
+		*out = new(HostGroupSelection)
+		(*in).DeepCopyInto(*out)
+	}
+	out.ConnectionDetails = in.ConnectionDetails
+	if in.MetadataTags != nil {
+		in, out := &in.MetadataTags, &out.MetadataTags
+		*out = new(MetadataTagOptions)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rightsizing != nil {
+		in, out := &in.Rightsizing, &out.Rightsizing
+		*out = new(RightsizingOptions)
+		**out = **in
+	}
+	in.Timeouts.DeepCopyInto(&out.Timeouts)
+	in.RestartDetection.DeepCopyInto(&out.RestartDetection)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProviderConfigSpec.
@@ -293,3 +437,58 @@ func (in *ProviderCredentials) DeepCopy() *ProviderCredentials {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestartDetectionOptions) DeepCopyInto(out *RestartDetectionOptions) {
+	*out = *in
+	if in.Window != nil {
+		in, out := &in.Window, &out.Window
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestartDetectionOptions.
+func (in *RestartDetectionOptions) DeepCopy() *RestartDetectionOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(RestartDetectionOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RightsizingOptions) DeepCopyInto(out *RightsizingOptions) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RightsizingOptions.
+func (in *RightsizingOptions) DeepCopy() *RightsizingOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(RightsizingOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSOptions) DeepCopyInto(out *TLSOptions) {
+	*out = *in
+	if in.CABundleSecretRef != nil {
+		in, out := &in.CABundleSecretRef, &out.CABundleSecretRef
+		*out = new(v1.SecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSOptions.
+func (in *TLSOptions) DeepCopy() *TLSOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSOptions)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+)
+
+// Condition types specific to Slicer VMs.
+const (
+	// TypeMaintenance indicates whether a VM is in maintenance mode.
+	TypeMaintenance xpv1.ConditionType = "Maintenance"
+
+	// TypeExpired indicates that a VM has outlived its TTL.
+	TypeExpired xpv1.ConditionType = "Expired"
+
+	// TypeForeignResource indicates whether the Slicer VM a resource refers to
+	// is managed by something else.
+	TypeForeignResource xpv1.ConditionType = "ForeignResource"
+
+	// TypeHealthy summarizes the health of a VM.
+	TypeHealthy xpv1.ConditionType = "Healthy"
+
+	// TypeResizeRecommended indicates whether a VM's observed usage suggests
+	// it should be resized.
+	TypeResizeRecommended xpv1.ConditionType = "ResizeRecommended"
+
+	// TypeFrequentRestarts indicates whether a VM is restarting frequently.
+	TypeFrequentRestarts xpv1.ConditionType = "FrequentRestarts"
+
+	// TypeCredentialsPending indicates whether a VM is waiting for the
+	// credentials secret of its provider config to be created.
+	TypeCredentialsPending xpv1.ConditionType = "CredentialsPending"
+
+	// TypeTagPolicyViolation indicates whether a VM violates a TagPolicy.
+	TypeTagPolicyViolation xpv1.ConditionType = "TagPolicyViolation"
+
+	// TypeRecreateRequired indicates whether a VM must be recreated for
+	// changes to its parameters to take effect.
+	TypeRecreateRequired xpv1.ConditionType = "RecreateRequired"
+
+	// TypeCreateRetriesExhausted indicates whether the provider has given up
+	// retrying to create a VM.
+	TypeCreateRetriesExhausted xpv1.ConditionType = "CreateRetriesExhausted"
+
+	// TypeTagsDrifted indicates whether a VM's tags differ from its desired
+	// tags.
+	TypeTagsDrifted xpv1.ConditionType = "TagsDrifted"
+
+	// TypeDryRun indicates whether the Slicer API would accept a VM in
+	// dry-run mode.
+	TypeDryRun xpv1.ConditionType = "DryRun"
+)
+
+// Condition reasons specific to Slicer VMs.
+const (
+	ReasonMaintenanceEnabled  xpv1.ConditionReason = "MaintenanceEnabled"
+	ReasonMaintenanceDisabled xpv1.ConditionReason = "MaintenanceDisabled"
+	ReasonTTLExpired          xpv1.ConditionReason = "TTLExpired"
+	ReasonNotOwned            xpv1.ConditionReason = "NotOwned"
+	ReasonOwned               xpv1.ConditionReason = "Owned"
+	ReasonHealthy             xpv1.ConditionReason = "Healthy"
+	ReasonUnhealthy           xpv1.ConditionReason = "Unhealthy"
+	ReasonOversized           xpv1.ConditionReason = "Oversized"
+	ReasonUndersized          xpv1.ConditionReason = "Undersized"
+	ReasonRightSized          xpv1.ConditionReason = "RightSized"
+	ReasonRestartingOften     xpv1.ConditionReason = "RestartingOften"
+	ReasonStable              xpv1.ConditionReason = "Stable"
+	ReasonSecretNotFound      xpv1.ConditionReason = "SecretNotFound"
+	ReasonCredentialsResolved xpv1.ConditionReason = "CredentialsResolved"
+	ReasonRequiredTagsMissing xpv1.ConditionReason = "RequiredTagsMissing"
+	ReasonTagPolicySatisfied  xpv1.ConditionReason = "TagPolicySatisfied"
+	ReasonCreateOnlyChanged   xpv1.ConditionReason = "CreateOnlyParametersChanged"
+	ReasonParametersApplied   xpv1.ConditionReason = "ParametersApplied"
+	ReasonGaveUp              xpv1.ConditionReason = "GaveUp"
+	ReasonRetrying            xpv1.ConditionReason = "Retrying"
+	ReasonTagsDiffer          xpv1.ConditionReason = "TagsDiffer"
+	ReasonTagsMatch           xpv1.ConditionReason = "TagsMatch"
+	ReasonDryRunAccepted      xpv1.ConditionReason = "Accepted"
+	ReasonDryRunRejected      xpv1.ConditionReason = "Rejected"
+	ReasonDryRunDisabled      xpv1.ConditionReason = "DryRunDisabled"
+)
+
+// MaintenanceEnabled returns a condition that indicates the VM is in
+// maintenance mode and alerting should ignore it.
+func MaintenanceEnabled() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeMaintenance,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonMaintenanceEnabled,
+	}
+}
+
+// MaintenanceDisabled returns a condition that indicates the VM has left
+// maintenance mode.
+func MaintenanceDisabled() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeMaintenance,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonMaintenanceDisabled,
+	}
+}
+
+// Expired returns a condition that indicates the VM has outlived its TTL and
+// is being deleted.
+func Expired() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeExpired,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTTLExpired,
+	}
+}
+
+// ForeignResource returns a condition that indicates the Slicer VM the
+// resource refers to is not owned by it, and will not be adopted.
+func ForeignResource() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeForeignResource,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNotOwned,
+	}
+}
+
+// OwnedResource returns a condition that indicates the Slicer VM the resource
+// refers to is owned by it.
+func OwnedResource() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeForeignResource,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOwned,
+	}
+}
+
+// Healthy returns a condition that indicates the VM is healthy.
+func Healthy() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeHealthy,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonHealthy,
+	}
+}
+
+// Unhealthy returns a condition that indicates the VM is unhealthy. Its
+// message should explain why.
+func Unhealthy() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeHealthy,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUnhealthy,
+	}
+}
+
+// ResizeRecommended returns a condition that recommends resizing the VM. The
+// reason must be ReasonOversized or ReasonUndersized, and its message should
+// describe the observed usage.
+func ResizeRecommended(r xpv1.ConditionReason) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeResizeRecommended,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             r,
+	}
+}
+
+// RightSized returns a condition that indicates the VM's size suits its
+// observed usage.
+func RightSized() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeResizeRecommended,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRightSized,
+	}
+}
+
+// FrequentRestarts returns a condition that indicates the VM is restarting
+// frequently, for example because it is crash-looping.
+func FrequentRestarts() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeFrequentRestarts,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRestartingOften,
+	}
+}
+
+// Stable returns a condition that indicates the VM is not restarting
+// frequently.
+func Stable() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeFrequentRestarts,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonStable,
+	}
+}
+
+// CredentialsPending returns a condition that indicates the credentials secret
+// of the VM's provider config does not exist yet.
+func CredentialsPending() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCredentialsPending,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSecretNotFound,
+	}
+}
+
+// CredentialsResolved returns a condition that indicates the credentials of
+// the VM's provider config were found.
+func CredentialsResolved() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCredentialsPending,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCredentialsResolved,
+	}
+}
+
+// TagPolicyViolation returns a condition that indicates the VM is missing
+// tags required by a TagPolicy, and will not be created.
+func TagPolicyViolation() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTagPolicyViolation,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRequiredTagsMissing,
+	}
+}
+
+// TagPolicySatisfied returns a condition that indicates the VM satisfies all
+// TagPolicies.
+func TagPolicySatisfied() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTagPolicyViolation,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTagPolicySatisfied,
+	}
+}
+
+// RecreateRequired returns a condition that indicates parameters of the VM
+// that can only be set on creation have changed, so the VM must be recreated
+// for them to take effect.
+func RecreateRequired() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeRecreateRequired,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonCreateOnlyChanged,
+	}
+}
+
+// ParametersApplied returns a condition that indicates all parameters of the
+// VM have been applied.
+func ParametersApplied() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeRecreateRequired,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonParametersApplied,
+	}
+}
+
+// CreateRetriesExhausted returns a condition that indicates the provider has
+// given up retrying to create the VM. Its message should say when retries
+// resume.
+func CreateRetriesExhausted() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCreateRetriesExhausted,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonGaveUp,
+	}
+}
+
+// CreateRetrying returns a condition that indicates the provider retries to
+// create the VM, or has created it.
+func CreateRetrying() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeCreateRetriesExhausted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonRetrying,
+	}
+}
+
+// TagsDrifted returns a condition that indicates the VM's tags differ from its
+// desired tags. Its message should describe the difference.
+func TagsDrifted() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTagsDrifted,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTagsDiffer,
+	}
+}
+
+// TagsInSync returns a condition that indicates the VM's tags match its
+// desired tags.
+func TagsInSync() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeTagsDrifted,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonTagsMatch,
+	}
+}
+
+// DryRunAccepted returns a condition that indicates a VM in dry-run mode would
+// be accepted.
+func DryRunAccepted() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDryRun,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDryRunAccepted,
+	}
+}
+
+// DryRunRejected returns a condition that indicates a VM in dry-run mode would
+// not be accepted. Its message should say why.
+func DryRunRejected() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDryRun,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDryRunRejected,
+	}
+}
+
+// DryRunDisabled returns a condition that indicates a VM is no longer in
+// dry-run mode.
+func DryRunDisabled() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeDryRun,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDryRunDisabled,
+	}
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// TagPolicySpec defines the tagging rules applied to VMs when they are
+// created. A tag's key is the part before its first "=", or the whole tag if
+// it has none.
+type TagPolicySpec struct {
+	// RequiredTagKeys are tag keys every VM must have a tag for. VMs missing
+	// any of them are not created.
+	// +optional
+	RequiredTagKeys []string `json:"requiredTagKeys,omitempty"`
+
+	// DefaultTags are added to VMs that have no tag with the same key.
+	// +optional
+	DefaultTags []string `json:"defaultTags,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A TagPolicy defines tagging rules that apply to all VMs. All TagPolicies in
+// the cluster apply.
+// +kubebuilder:printcolumn:name="REQUIRED",type="string",JSONPath=".spec.requiredTagKeys"
+// +kubebuilder:printcolumn:name="DEFAULTS",type="string",JSONPath=".spec.defaultTags"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,slicervm}
+type TagPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec TagPolicySpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// TagPolicyList contains a list of TagPolicy
+type TagPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TagPolicy `json:"items"`
+}
+
+// TagPolicy type metadata.
+var (
+	TagPolicyKind             = reflect.TypeOf(TagPolicy{}).Name()
+	TagPolicyGroupKind        = schema.GroupKind{Group: Group, Kind: TagPolicyKind}.String()
+	TagPolicyKindAPIVersion   = TagPolicyKind + "." + SchemeGroupVersion.String()
+	TagPolicyGroupVersionKind = SchemeGroupVersion.WithKind(TagPolicyKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&TagPolicy{}, &TagPolicyList{})
+}
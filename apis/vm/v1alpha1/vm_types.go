@@ -19,6 +19,7 @@ package v1alpha1
 import (
 	"reflect"
 
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
@@ -30,16 +31,25 @@ import (
 type VMParameters struct {
 	// HostGroup is the host group to create the VM in.
 	// If not specified, the default host group from the ProviderConfig is used.
+	// VMs cannot be moved between host groups once created.
 	// +optional
 	HostGroup string `json:"hostGroup,omitempty"`
 
-	// CPUs is the number of virtual CPUs for the VM.
-	// +kubebuilder:default=2
+	// EndpointOverride is the Slicer API endpoint URL to manage the VM with,
+	// instead of the URL of its provider config. It must be one of the
+	// provider config's allowedEndpointOverrides. The provider config's
+	// credentials are still used. It cannot be changed once set.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="endpointOverride is immutable"
+	// +optional
+	EndpointOverride string `json:"endpointOverride,omitempty"`
+
+	// CPUs is the number of virtual CPUs for the VM. If not specified, the
+	// provider config's defaultCpus is used, or 2 if it has none.
 	// +optional
 	CPUs int `json:"cpus,omitempty"`
 
-	// RAMGB is the amount of RAM in GB for the VM.
-	// +kubebuilder:default=4
+	// RAMGB is the amount of RAM in GB for the VM. If not specified, the
+	// provider config's defaultRamGb is used, or 4 if it has none.
 	// +optional
 	RAMGB int `json:"ramGb,omitempty"`
 
@@ -47,10 +57,22 @@ type VMParameters struct {
 	// +optional
 	Userdata string `json:"userdata,omitempty"`
 
+	// UserdataFrom reads the cloud-init userdata script from a ConfigMap or
+	// Secret in the VM's namespace when the VM is created. It is ignored if
+	// Userdata is set.
+	// +optional
+	UserdataFrom *UserdataSource `json:"userdataFrom,omitempty"`
+
 	// SSHKeys is a list of SSH public keys to add to the VM.
 	// +optional
 	SSHKeys []string `json:"sshKeys,omitempty"`
 
+	// SSHKeySecretRefs are keys of Secrets in the VM's namespace holding SSH
+	// public keys to add to the VM, in addition to SSHKeys. A key may hold
+	// several public keys, one per line.
+	// +optional
+	SSHKeySecretRefs []xpv1.LocalSecretKeySelector `json:"sshKeySecretRefs,omitempty"`
+
 	// ImportUser is a GitHub username to import SSH keys from.
 	// +optional
 	ImportUser string `json:"importUser,omitempty"`
@@ -58,21 +80,258 @@ type VMParameters struct {
 	// Tags are labels to apply to the VM.
 	// +optional
 	Tags []string `json:"tags,omitempty"`
+
+	// Metadata is key/value metadata to apply to the VM. Slicer only supports
+	// string tags, so each entry is applied as a key=value tag. Tags with the
+	// same key take precedence. Keys must not contain "=".
+	// +optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// MaintenanceMode marks the VM as being in maintenance by setting a
+	// Maintenance condition, so that external alerting can ignore it. The VM
+	// continues to be observed as normal.
+	// +optional
+	MaintenanceMode bool `json:"maintenanceMode,omitempty"`
+
+	// HealthCheck checks a service running on the VM once it is running,
+	// and reflects the result in the VM's Healthy condition.
+	// +optional
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+
+	// TTLSeconds is how long the VM may live after it was created. Once its
+	// TTL has passed the VM is deleted. Unset the field to disable expiry.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TTLSeconds *int64 `json:"ttlSeconds,omitempty"`
+
+	// ShutdownBeforeDelete asks the VM's guest to shut down before the VM is
+	// deleted, so that stateful workloads can stop cleanly. The VM is deleted
+	// once its guest agent stops reporting, or after the provider config's
+	// shutdown timeout.
+	// +optional
+	ShutdownBeforeDelete bool `json:"shutdownBeforeDelete,omitempty"`
+
+	// WaitForCloudInit keeps the VM from becoming available until cloud-init
+	// has finished running its userdata. A VM on which cloud-init fails, or
+	// does not finish within the provider config's cloud-init timeout, is
+	// reported as unavailable.
+	// +optional
+	WaitForCloudInit bool `json:"waitForCloudInit,omitempty"`
+
+	// ExtraFields are additional fields merged into the body of the Slicer
+	// create request. They allow API features the provider does not model yet
+	// to be used, and must not conflict with modeled fields.
+	// +optional
+	ExtraFields map[string]extv1.JSON `json:"extraFields,omitempty"`
+}
+
+// AnnotationPollInterval requests a different poll interval for an Available
+// VM, as a duration such as 15s or 10m.
+const AnnotationPollInterval = "slicervm.crossplane.io/poll-interval"
+
+// A UserdataSource selects a key of a ConfigMap or Secret holding userdata.
+// +kubebuilder:validation:XValidation:rule="has(self.configMapKeyRef) != has(self.secretKeyRef)",message="exactly one of configMapKeyRef and secretKeyRef must be set"
+type UserdataSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap.
+	// +optional
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret.
+	// +optional
+	SecretKeyRef *xpv1.LocalSecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// HealthCheckProtocol is the protocol of a health check.
+// +kubebuilder:validation:Enum=TCP;HTTP
+type HealthCheckProtocol string
+
+// Health check protocols.
+const (
+	// HealthCheckTCP checks that a port of the VM accepts TCP connections.
+	HealthCheckTCP HealthCheckProtocol = "TCP"
+
+	// HealthCheckHTTP checks that an HTTP GET request to the VM succeeds.
+	HealthCheckHTTP HealthCheckProtocol = "HTTP"
+)
+
+// A HealthCheck checks a service running on a VM, at the VM's IP address.
+type HealthCheck struct {
+	// Protocol of the check. TCP checks that the port accepts connections.
+	// HTTP checks that a GET request of the path is answered with a status
+	// below 400. Defaults to TCP.
+	// +kubebuilder:default=TCP
+	// +optional
+	Protocol HealthCheckProtocol `json:"protocol,omitempty"`
+
+	// Port of the VM to check.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+
+	// Path requested by HTTP checks. Defaults to /.
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Timeout of the check. Defaults to 2s, and is bounded by the provider
+	// config's observe timeout.
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// AffectsReadiness reports the VM as unavailable while the check fails.
+	// By default a failing check only makes the VM unhealthy.
+	// +optional
+	AffectsReadiness bool `json:"affectsReadiness,omitempty"`
 }
 
+// A ConfigMapKeySelector selects a key of a ConfigMap in the same namespace as
+// the referencing object.
+type ConfigMapKeySelector struct {
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Key of the ConfigMap to select.
+	Key string `json:"key"`
+}
+
+// ManagedBy indicates who created a Slicer VM.
+type ManagedBy string
+
+// Creators of Slicer VMs.
+const (
+	// ManagedByProvider indicates the VM was created by the provider for the
+	// resource observing it.
+	ManagedByProvider ManagedBy = "Provider"
+
+	// ManagedByOtherResource indicates the VM was created by the provider for
+	// another resource.
+	ManagedByOtherResource ManagedBy = "OtherResource"
+
+	// ManagedByExternal indicates the VM has no owner tag, because it was
+	// created outside the provider, or by a provider version that did not
+	// tag VMs.
+	ManagedByExternal ManagedBy = "External"
+)
+
 // VMObservation are the observable fields of a Slicer VM.
 type VMObservation struct {
 	// Hostname is the hostname of the VM.
 	Hostname string `json:"hostname,omitempty"`
 
+	// HostGroup is the host group the VM was created in.
+	HostGroup string `json:"hostGroup,omitempty"`
+
 	// IP is the IP address of the VM.
 	IP string `json:"ip,omitempty"`
 
+	// Arch is the CPU architecture of the VM, for example x86_64.
+	Arch string `json:"arch,omitempty"`
+
+	// Tags are the tags of the VM, as reported by Slicer.
+	Tags []string `json:"tags,omitempty"`
+
+	// Metadata is the key/value metadata of the VM: its key=value tags other
+	// than the owner tag.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
 	// State is the current state of the VM.
 	State string `json:"state,omitempty"`
 
+	// ManagedBy indicates who created the VM, based on its owner tag.
+	ManagedBy ManagedBy `json:"managedBy,omitempty"`
+
 	// CreatedAt is the creation timestamp of the VM.
 	CreatedAt string `json:"createdAt,omitempty"`
+
+	// CreateParametersHash is a hash of the parameters that can only be set
+	// when the VM is created, as they were when it was created.
+	CreateParametersHash string `json:"createParametersHash,omitempty"`
+
+	// CreateAttempts is the number of consecutive failed attempts to create
+	// the VM. It is reset once the VM is created.
+	CreateAttempts int `json:"createAttempts,omitempty"`
+
+	// LastCreateError is the error of the last failed attempt to create the
+	// VM. It is cleared once the VM is created.
+	LastCreateError string `json:"lastCreateError,omitempty"`
+
+	// CreateFailingSince is the time of the first of the consecutive failed
+	// attempts to create the VM. It is cleared once the VM is created.
+	CreateFailingSince *metav1.Time `json:"createFailingSince,omitempty"`
+
+	// CPUs is the number of CPUs the VM's guest agent reports.
+	CPUs int `json:"cpus,omitempty"`
+
+	// MemoryBytes is the total memory the VM's guest agent reports. It is
+	// slightly less than the RAM the VM was created with, as the guest
+	// kernel reserves some.
+	MemoryBytes *int64 `json:"memoryBytes,omitempty"`
+
+	// GuestAgentReady indicates whether the VM's guest agent is reporting in.
+	// It is true when the Slicer API does not report agent status.
+	GuestAgentReady bool `json:"guestAgentReady,omitempty"`
+
+	// CloudInitDone indicates whether cloud-init has finished on a VM with
+	// waitForCloudInit.
+	CloudInitDone bool `json:"cloudInitDone,omitempty"`
+
+	// Reachable indicates whether the VM accepted a TCP connection on its SSH
+	// port. It is only set when reachability probing is enabled.
+	Reachable *bool `json:"reachable,omitempty"`
+
+	// Latency is how long establishing the TCP connection to the VM took.
+	Latency *metav1.Duration `json:"latency,omitempty"`
+
+	// HealthCheckPassing indicates whether the VM's health check passed. It
+	// is only set for running VMs with a health check.
+	HealthCheckPassing *bool `json:"healthCheckPassing,omitempty"`
+
+	// HealthCheckError is why the VM's health check failed.
+	HealthCheckError string `json:"healthCheckError,omitempty"`
+
+	// PublicIP indicates whether the VM's IP address is publicly routable.
+	PublicIP bool `json:"publicIP,omitempty"`
+
+	// SSHExposed indicates whether SSH access is configured for a VM with a
+	// publicly routable IP address.
+	SSHExposed bool `json:"sshExposed,omitempty"`
+
+	// DiskReadBytes is the total number of bytes the VM has read from disk,
+	// as reported by its guest agent.
+	DiskReadBytes *int64 `json:"diskReadBytes,omitempty"`
+
+	// DiskSizeBytes is the size of the VM's root filesystem, as reported by
+	// its guest agent.
+	DiskSizeBytes *int64 `json:"diskSizeBytes,omitempty"`
+
+	// DiskWriteBytes is the total number of bytes the VM has written to
+	// disk, as reported by its guest agent.
+	DiskWriteBytes *int64 `json:"diskWriteBytes,omitempty"`
+
+	// DiskIOInflight is the number of disk I/O operations in progress when
+	// the VM was last observed.
+	DiskIOInflight *int64 `json:"diskIOInflight,omitempty"`
+
+	// BootTime is when the VM last booted, derived from its reported uptime.
+	BootTime *metav1.Time `json:"bootTime,omitempty"`
+
+	// RestartCount is the number of times the VM has been observed to
+	// restart, whether requested or not.
+	RestartCount int `json:"restartCount,omitempty"`
+
+	// RecentRestarts are the times of the VM's restarts within the restart
+	// detection window.
+	RecentRestarts []metav1.Time `json:"recentRestarts,omitempty"`
+
+	// ShutdownRequestedAt is when the VM was asked to shut down before being
+	// deleted.
+	ShutdownRequestedAt *metav1.Time `json:"shutdownRequestedAt,omitempty"`
+
+	// LastRebootRequest is the value of the reboot annotation that was last
+	// acted upon.
+	LastRebootRequest string `json:"lastRebootRequest,omitempty"`
+
+	// LastRebootTime is the time the VM was last rebooted by the provider.
+	LastRebootTime *metav1.Time `json:"lastRebootTime,omitempty"`
 }
 
 // A VMSpec defines the desired state of a Slicer VM.
@@ -95,6 +354,10 @@ type VMStatus struct {
 // +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
 // +kubebuilder:printcolumn:name="HOSTNAME",type="string",JSONPath=".status.atProvider.hostname"
 // +kubebuilder:printcolumn:name="IP",type="string",JSONPath=".status.atProvider.ip"
+// +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.atProvider.state"
+// +kubebuilder:printcolumn:name="HOST-GROUP",type="string",JSONPath=".status.atProvider.hostGroup",priority=1
+// +kubebuilder:printcolumn:name="CPUS",type="integer",JSONPath=".status.atProvider.cpus",priority=1
+// +kubebuilder:printcolumn:name="ARCH",type="string",JSONPath=".status.atProvider.arch",priority=1
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Namespaced,categories={crossplane,managed,slicervm}
@@ -9,9 +9,155 @@
 package v1alpha1
 
 import (
+	"github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigMapKeySelector) DeepCopyInto(out *ConfigMapKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigMapKeySelector.
+func (in *ConfigMapKeySelector) DeepCopy() *ConfigMapKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigMapKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagPolicy) DeepCopyInto(out *TagPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagPolicy.
+func (in *TagPolicy) DeepCopy() *TagPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(TagPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TagPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagPolicyList) DeepCopyInto(out *TagPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]TagPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagPolicyList.
+func (in *TagPolicyList) DeepCopy() *TagPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(TagPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TagPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TagPolicySpec) DeepCopyInto(out *TagPolicySpec) {
+	*out = *in
+	if in.RequiredTagKeys != nil {
+		in, out := &in.RequiredTagKeys, &out.RequiredTagKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DefaultTags != nil {
+		in, out := &in.DefaultTags, &out.DefaultTags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TagPolicySpec.
+func (in *TagPolicySpec) DeepCopy() *TagPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TagPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserdataSource) DeepCopyInto(out *UserdataSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(ConfigMapKeySelector)
+		**out = **in
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(v1.LocalSecretKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserdataSource.
+func (in *UserdataSource) DeepCopy() *UserdataSource {
+	if in == nil {
+		return nil
+	}
+	out := new(UserdataSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VM) DeepCopyInto(out *VM) {
 	*out = *in
@@ -74,6 +220,81 @@ func (in *VMList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMObservation) DeepCopyInto(out *VMObservation) {
 	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CreateFailingSince != nil {
+		in, out := &in.CreateFailingSince, &out.CreateFailingSince
+		*out = (*in).DeepCopy()
+	}
+	if in.MemoryBytes != nil {
+		in, out := &in.MemoryBytes, &out.MemoryBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Reachable != nil {
+		in, out := &in.Reachable, &out.Reachable
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Latency != nil {
+		in, out := &in.Latency, &out.Latency
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.HealthCheckPassing != nil {
+		in, out := &in.HealthCheckPassing, &out.HealthCheckPassing
+		*out = new(bool)
+		**out = **in
+	}
+	if in.DiskReadBytes != nil {
+		in, out := &in.DiskReadBytes, &out.DiskReadBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DiskSizeBytes != nil {
+		in, out := &in.DiskSizeBytes, &out.DiskSizeBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DiskWriteBytes != nil {
+		in, out := &in.DiskWriteBytes, &out.DiskWriteBytes
+		*out = new(int64)
+		**out = **in
+	}
+	if in.DiskIOInflight != nil {
+		in, out := &in.DiskIOInflight, &out.DiskIOInflight
+		*out = new(int64)
+		**out = **in
+	}
+	if in.BootTime != nil {
+		in, out := &in.BootTime, &out.BootTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RecentRestarts != nil {
+		in, out := &in.RecentRestarts, &out.RecentRestarts
+		*out = make([]metav1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ShutdownRequestedAt != nil {
+		in, out := &in.ShutdownRequestedAt, &out.ShutdownRequestedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.LastRebootTime != nil {
+		in, out := &in.LastRebootTime, &out.LastRebootTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMObservation.
@@ -89,16 +310,50 @@ func (in *VMObservation) DeepCopy() *VMObservation {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMParameters) DeepCopyInto(out *VMParameters) {
 	*out = *in
+	if in.UserdataFrom != nil {
+		in, out := &in.UserdataFrom, &out.UserdataFrom
+		*out = new(UserdataSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.SSHKeys != nil {
 		in, out := &in.SSHKeys, &out.SSHKeys
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.SSHKeySecretRefs != nil {
+		in, out := &in.SSHKeySecretRefs, &out.SSHKeySecretRefs
+		*out = make([]v1.LocalSecretKeySelector, len(*in))
+		copy(*out, *in)
+	}
 	if in.Tags != nil {
 		in, out := &in.Tags, &out.Tags
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Metadata != nil {
+		in, out := &in.Metadata, &out.Metadata
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(HealthCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TTLSeconds != nil {
+		in, out := &in.TTLSeconds, &out.TTLSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.ExtraFields != nil {
+		in, out := &in.ExtraFields, &out.ExtraFields
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMParameters.
@@ -132,7 +387,7 @@ func (in *VMSpec) DeepCopy() *VMSpec {
 func (in *VMStatus) DeepCopyInto(out *VMStatus) {
 	*out = *in
 	in.ResourceStatus.DeepCopyInto(&out.ResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMStatus.
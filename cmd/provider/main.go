@@ -31,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	changelogsv1alpha1 "github.com/crossplane/crossplane-runtime/v2/apis/changelogs/proto/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
@@ -46,7 +47,12 @@ import (
 
 	"github.com/gaarutyunov/provider-slicervm/apis"
 	slicervm "github.com/gaarutyunov/provider-slicervm/internal/controller"
+	"github.com/gaarutyunov/provider-slicervm/internal/controller/inventory"
+	"github.com/gaarutyunov/provider-slicervm/internal/controller/vm"
+	"github.com/gaarutyunov/provider-slicervm/internal/features"
+	slicermetrics "github.com/gaarutyunov/provider-slicervm/internal/metrics"
 	"github.com/gaarutyunov/provider-slicervm/internal/version"
+	slicerwebhook "github.com/gaarutyunov/provider-slicervm/internal/webhook"
 )
 
 func main() {
@@ -58,12 +64,39 @@ func main() {
 		syncInterval            = app.Flag("sync", "How often all resources will be double-checked for drift from the desired state.").Short('s').Default("1h").Duration()
 		pollInterval            = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
 		pollStateMetricInterval = app.Flag("poll-state-metric", "State metric recording interval").Default("5s").Duration()
-
-		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
+		minPollInterval         = app.Flag("min-poll", "The shortest poll interval an individual VM may request using the poll interval annotation.").Default("10s").Duration()
+		creationGracePeriod     = app.Flag("creation-grace-period", "How long after creating a VM it is assumed to still be provisioning if the Slicer API does not list it yet. 0 disables it.").Default("30s").Duration()
+
+		maxReconcileRate      = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
+		vmBackoffBase         = app.Flag("vm-backoff-base", "The initial backoff before retrying a VM whose reconcile failed. Backoff is tracked per VM. Zero keeps the default rate limiter.").Default("0").Duration()
+		vmBackoffMax          = app.Flag("vm-backoff-max", "The maximum backoff before retrying a VM whose reconciles keep failing. Zero keeps the default rate limiter.").Default("0").Duration()
+		vmCreateRetryLimit    = app.Flag("vm-create-retry-limit", "How long failed attempts to create a VM are retried before giving up. Zero retries forever.").Default("0").Duration()
+		vmCreateRetryCooldown = app.Flag("vm-create-retry-cooldown", "How long after giving up creating a VM retries start over.").Default("1h").Duration()
+		vmNodeListTTL         = app.Flag("vm-node-list-ttl", "How long a listing of the VMs of a host group is reused when observing other VMs of the host group.").Default("1s").Duration()
+		vmStatsTTL            = app.Flag("vm-stats-ttl", "How long a listing of the stats of all VMs is reused when observing VMs.").Default("30s").Duration()
+		vmNodeListSharing     = app.Flag("vm-node-list-sharing", "Share listings of the VMs of a host group between VMs observed together. Disable to list the host group for every VM.").Default("true").Bool()
+		vmMaxReconciles       = app.Flag("vm-max-concurrent-reconciles", "The maximum number of concurrent VM reconciles. Defaults to the max-reconcile-rate.").Default("0").Int()
 
 		enableManagementPolicies = app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool()
 		enableChangeLogs         = app.Flag("enable-changelogs", "Enable support for capturing change logs during reconciliation.").Default("false").Envar("ENABLE_CHANGE_LOGS").Bool()
 		changelogsSocketPath     = app.Flag("changelogs-socket-path", "Path for changelogs socket (if enabled)").Default("/var/run/changelogs/changelogs.sock").Envar("CHANGELOGS_SOCKET_PATH").String()
+
+		enableGuestAgentReadiness = app.Flag("enable-guest-agent-readiness", "Only report VMs as available once their guest agent has reported in.").Default("false").Envar("ENABLE_GUEST_AGENT_READINESS").Bool()
+		enableReachabilityProbe   = app.Flag("enable-reachability-probe", "Probe whether VMs accept TCP connections on their SSH port, and only report reachable VMs as available.").Default("false").Envar("ENABLE_REACHABILITY_PROBE").Bool()
+
+		tlsServerCertsDir = app.Flag("tls-server-certs-dir", "The directory containing the webhook server's TLS certificate and key. Admission webhooks are only served if set.").Envar("TLS_SERVER_CERTS_DIR").String()
+		defaultHostGroup  = app.Flag("default-host-group", "Host group injected into new VMs that don't set one.").Envar("DEFAULT_HOST_GROUP").String()
+		defaultTags       = app.Flag("default-tag", "Tag injected into new VMs that don't set any tags. May be repeated.").Strings()
+		defaultSSHKeys    = app.Flag("default-ssh-key", "SSH public key injected into new VMs that don't set any keys. May be repeated.").Strings()
+		minVMCPUs         = app.Flag("min-vm-cpus", "The fewest CPUs a VM may request. Enforced by the validating webhook.").Default("1").Int()
+		maxVMCPUs         = app.Flag("max-vm-cpus", "The most CPUs a VM may request. Enforced by the validating webhook. Zero means no limit.").Default("0").Int()
+		minVMRAMGB        = app.Flag("min-vm-ram-gb", "The least RAM in GB a VM may request. Enforced by the validating webhook.").Default("1").Int()
+		maxVMRAMGB        = app.Flag("max-vm-ram-gb", "The most RAM in GB a VM may request. Enforced by the validating webhook. Zero means no limit.").Default("0").Int()
+
+		enableInventory    = app.Flag("enable-inventory", "Periodically export the inventory of managed VMs to a ConfigMap.").Default("false").Envar("ENABLE_INVENTORY").Bool()
+		inventoryNamespace = app.Flag("inventory-namespace", "Namespace of the VM inventory ConfigMap.").Default("crossplane-system").Envar("INVENTORY_NAMESPACE").String()
+		inventoryName      = app.Flag("inventory-name", "Name of the VM inventory ConfigMap.").Default("slicervm-inventory").Envar("INVENTORY_NAME").String()
+		inventoryInterval  = app.Flag("inventory-interval", "How often the VM inventory ConfigMap is written.").Default("5m").Duration()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -84,6 +117,10 @@ func main() {
 	kingpin.FatalIfError(err, "Cannot get API server rest config")
 
 	mgr, err := ctrl.NewManager(ratelimiter.LimitRESTConfig(cfg, *maxReconcileRate), ctrl.Options{
+		WebhookServer: webhook.NewServer(webhook.Options{
+			CertDir: *tlsServerCertsDir,
+		}),
+
 		// SyncPeriod in ctrl.Options has been removed since controller-runtime v0.16.0
 		// The recommended way is to move it to cache.Options instead
 		Cache: cache.Options{
@@ -111,8 +148,13 @@ func main() {
 	metricRecorder := managed.NewMRMetricRecorder()
 	stateMetrics := statemetrics.NewMRStateMetrics()
 
+	vmStates := slicermetrics.NewVMStates()
+	apiCalls := slicermetrics.NewAPICalls()
+
 	metrics.Registry.MustRegister(metricRecorder)
 	metrics.Registry.MustRegister(stateMetrics)
+	metrics.Registry.MustRegister(vmStates)
+	metrics.Registry.MustRegister(apiCalls)
 
 	o := controller.Options{
 		Logger:                  log,
@@ -148,7 +190,57 @@ func main() {
 		o.ChangeLogOptions = &clo
 	}
 
+	if *enableGuestAgentReadiness {
+		o.Features.Enable(features.EnableAlphaGuestAgentReadiness)
+		log.Info("Alpha feature enabled", "flag", features.EnableAlphaGuestAgentReadiness)
+	}
+
+	if *enableReachabilityProbe {
+		o.Features.Enable(features.EnableAlphaReachabilityProbe)
+		log.Info("Alpha feature enabled", "flag", features.EnableAlphaReachabilityProbe)
+	}
+
 	kingpin.FatalIfError(customresourcesgate.Setup(mgr, o), "Cannot setup CRD gate controller")
-	kingpin.FatalIfError(slicervm.SetupGated(mgr, o), "Cannot setup Slicer controllers")
+	vo := vm.Options{
+		MinPollInterval:         *minPollInterval,
+		MaxConcurrentReconciles: *vmMaxReconciles,
+		CreationGracePeriod:     *creationGracePeriod,
+		BackoffBase:             *vmBackoffBase,
+		BackoffMax:              *vmBackoffMax,
+		CreateRetryLimit:        *vmCreateRetryLimit,
+		CreateRetryCooldown:     *vmCreateRetryCooldown,
+		NodeListTTL:             *vmNodeListTTL,
+		DisableNodeListSharing:  !*vmNodeListSharing,
+		StatsTTL:                *vmStatsTTL,
+		StateMetrics:            vmStates,
+		APIMetrics:              apiCalls,
+	}
+
+	kingpin.FatalIfError(slicervm.SetupGated(mgr, o, vo), "Cannot setup Slicer controllers")
+
+	if *tlsServerCertsDir != "" {
+		d := slicerwebhook.VMDefaults{
+			HostGroup: *defaultHostGroup,
+			Tags:      *defaultTags,
+			SSHKeys:   *defaultSSHKeys,
+		}
+		l := slicerwebhook.VMLimits{
+			MinCPUs:  *minVMCPUs,
+			MaxCPUs:  *maxVMCPUs,
+			MinRAMGB: *minVMRAMGB,
+			MaxRAMGB: *maxVMRAMGB,
+		}
+		kingpin.FatalIfError(slicerwebhook.SetupVM(mgr, d, l), "Cannot setup VM webhooks")
+	}
+
+	if *enableInventory {
+		iv := inventory.Options{
+			Namespace: *inventoryNamespace,
+			Name:      *inventoryName,
+			Interval:  *inventoryInterval,
+		}
+		kingpin.FatalIfError(inventory.Setup(mgr, o, iv), "Cannot setup VM inventory")
+	}
+
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
 }
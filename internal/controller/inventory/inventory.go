@@ -0,0 +1,162 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package inventory periodically exports the managed VMs to a ConfigMap.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+const (
+	errListVMs        = "cannot list VMs"
+	errMarshal        = "cannot marshal VM inventory"
+	errGetConfigMap   = "cannot get inventory ConfigMap"
+	errApplyConfigMap = "cannot write inventory ConfigMap"
+)
+
+// KeyVMs is the ConfigMap data key holding the VM inventory as a JSON array.
+const KeyVMs = "vms.json"
+
+// Options configures the VM inventory.
+type Options struct {
+	// Namespace and Name identify the ConfigMap the inventory is written to.
+	Namespace string
+	Name      string
+
+	// Interval is how often the inventory is written.
+	Interval time.Duration
+}
+
+// An Entry describes a single VM in the inventory.
+type Entry struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Hostname  string   `json:"hostname,omitempty"`
+	IP        string   `json:"ip,omitempty"`
+	HostGroup string   `json:"hostGroup,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// Setup adds a runnable that periodically writes the inventory of managed VMs
+// to a ConfigMap. It only runs on the elected leader.
+func Setup(mgr ctrl.Manager, o controller.Options, iv Options) error {
+	return mgr.Add(&exporter{
+		kube:   mgr.GetClient(),
+		reader: mgr.GetAPIReader(),
+		log:    o.Logger.WithValues("runnable", "vm-inventory"),
+		opts:   iv,
+	})
+}
+
+type exporter struct {
+	kube client.Client
+	// reader reads the ConfigMap directly from the API server, so the
+	// provider does not cache every ConfigMap in the cluster.
+	reader client.Reader
+	log    logging.Logger
+	opts   Options
+}
+
+// Start writes the inventory every interval until the context is done.
+func (e *exporter) Start(ctx context.Context) error {
+	t := time.NewTicker(e.opts.Interval)
+	defer t.Stop()
+
+	for {
+		if err := e.export(ctx); err != nil {
+			e.log.Info("Cannot export VM inventory", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (e *exporter) export(ctx context.Context) error {
+	l := &v1alpha1.VMList{}
+	if err := e.kube.List(ctx, l); err != nil {
+		return errors.Wrap(err, errListVMs)
+	}
+
+	entries := make([]Entry, 0, len(l.Items))
+	for _, vm := range l.Items {
+		// VMs relying on a provider config default or host group selection
+		// only record their host group in their status once created.
+		hostGroup := vm.Status.AtProvider.HostGroup
+		if hostGroup == "" {
+			hostGroup = vm.Spec.ForProvider.HostGroup
+		}
+		entries = append(entries, Entry{
+			Namespace: vm.GetNamespace(),
+			Name:      vm.GetName(),
+			Hostname:  vm.Status.AtProvider.Hostname,
+			IP:        vm.Status.AtProvider.IP,
+			HostGroup: hostGroup,
+			Tags:      vm.Spec.ForProvider.Tags,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Namespace != entries[j].Namespace {
+			return entries[i].Namespace < entries[j].Namespace
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return errors.Wrap(err, errMarshal)
+	}
+
+	cm := &corev1.ConfigMap{}
+	err = e.reader.Get(ctx, types.NamespacedName{Namespace: e.opts.Namespace, Name: e.opts.Name}, cm)
+	switch {
+	case kerrors.IsNotFound(err):
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: e.opts.Namespace, Name: e.opts.Name},
+			Data:       map[string]string{KeyVMs: string(data)},
+		}
+		return errors.Wrap(e.kube.Create(ctx, cm), errApplyConfigMap)
+	case err != nil:
+		return errors.Wrap(err, errGetConfigMap)
+	}
+
+	if cm.Data[KeyVMs] == string(data) {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[KeyVMs] = string(data)
+	return errors.Wrap(e.kube.Update(ctx, cm), errApplyConfigMap)
+}
@@ -26,10 +26,10 @@ import (
 
 // SetupGated creates all Slicer controllers with safe-start support and adds them to
 // the supplied manager.
-func SetupGated(mgr ctrl.Manager, o controller.Options) error {
+func SetupGated(mgr ctrl.Manager, o controller.Options, vo vm.Options) error {
 	for _, setup := range []func(ctrl.Manager, controller.Options) error{
 		config.Setup,
-		vm.SetupGated,
+		func(mgr ctrl.Manager, o controller.Options) error { return vm.SetupGated(mgr, o, vo) },
 	} {
 		if err := setup(mgr, o); err != nil {
 			return err
@@ -0,0 +1,168 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/internal/metrics"
+)
+
+const (
+	errPublishClusterSecret   = "cannot publish cluster connection secret"
+	errUnpublishClusterSecret = "cannot delete cluster connection secret"
+	errClusterSecretNotOwned  = "refusing to overwrite a secret that was not published for this VM"
+)
+
+// Labels identifying the VM a cluster connection secret was published for.
+const (
+	labelVMNamespace = "slicervm.crossplane.io/vm-namespace"
+	labelVMName      = "slicervm.crossplane.io/vm-name"
+	labelVMUID       = "slicervm.crossplane.io/vm-uid"
+)
+
+// clusterSecretFor returns the cluster connection secret of the supplied VM,
+// or nil if the VM does not publish one. The secret lives outside the VM's
+// namespace, so it cannot be owned by the VM and is deleted explicitly.
+func (e *external) clusterSecretFor(cr *v1alpha1.VM) *corev1.Secret {
+	ref := cr.GetWriteConnectionSecretToReference()
+	if e.clusterSecretNamespace == "" || ref == nil {
+		return nil
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: e.clusterSecretNamespace,
+			Name:      cr.GetNamespace() + "-" + ref.Name,
+			Labels: map[string]string{
+				labelVMNamespace: cr.GetNamespace(),
+				labelVMName:      cr.GetName(),
+				labelVMUID:       string(cr.GetUID()),
+			},
+		},
+		Type: resource.SecretTypeConnection,
+	}
+}
+
+// publishClusterSecret publishes the supplied connection details to the
+// cluster connection secret of the supplied VM, if it has one. Like the VM's
+// own connection secret, publishing is additive. The secret is only written
+// if it is missing or its contents change.
+func (e *external) publishClusterSecret(ctx context.Context, cr *v1alpha1.VM, cd managed.ConnectionDetails) error {
+	want := e.clusterSecretFor(cr)
+	if want == nil {
+		return nil
+	}
+
+	current := &corev1.Secret{}
+	err := e.kube.Get(ctx, types.NamespacedName{Namespace: want.GetNamespace(), Name: want.GetName()}, current)
+	if kerrors.IsNotFound(err) {
+		want.Data = cd
+		return errors.Wrap(e.kube.Create(ctx, want), errPublishClusterSecret)
+	}
+	if err != nil {
+		return errors.Wrap(err, errPublishClusterSecret)
+	}
+	if current.GetLabels()[labelVMUID] != string(cr.GetUID()) {
+		return errors.New(errClusterSecretNotOwned)
+	}
+
+	changed := false
+	if current.Data == nil {
+		current.Data = map[string][]byte{}
+	}
+	for k, v := range cd {
+		if !bytes.Equal(current.Data[k], v) {
+			current.Data[k] = v
+			changed = true
+		}
+	}
+	for k, v := range want.GetLabels() {
+		if current.GetLabels()[k] != v {
+			meta.AddLabels(current, map[string]string{k: v})
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return errors.Wrap(e.kube.Update(ctx, current), errPublishClusterSecret)
+}
+
+// unpublishClusterSecret deletes the cluster connection secret of the
+// supplied VM, if it has one and it was published for the VM.
+func (e *external) unpublishClusterSecret(ctx context.Context, cr *v1alpha1.VM) error {
+	s := e.clusterSecretFor(cr)
+	if s == nil {
+		return nil
+	}
+	current := &corev1.Secret{}
+	err := e.kube.Get(ctx, types.NamespacedName{Namespace: s.GetNamespace(), Name: s.GetName()}, current)
+	if kerrors.IsNotFound(err) || (err == nil && current.GetLabels()[labelVMUID] != string(cr.GetUID())) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, errUnpublishClusterSecret)
+	}
+	return errors.Wrap(resource.IgnoreNotFound(e.kube.Delete(ctx, current, client.Preconditions{UID: &current.UID})), errUnpublishClusterSecret)
+}
+
+// A clusterSecretFinalizer deletes the cluster connection secret of a VM and
+// stops counting it in the state metrics before removing its finalizer. VMs
+// orphaned by their management policies are neither observed nor deleted
+// while their resource is deleted, so neither is done by Observe or Delete.
+type clusterSecretFinalizer struct {
+	resource.Finalizer
+
+	kube    client.Client
+	metrics *metrics.VMStates
+}
+
+// RemoveFinalizer deletes the cluster connection secret of the supplied VM,
+// if it has one, stops counting it and removes its finalizer.
+func (f *clusterSecretFinalizer) RemoveFinalizer(ctx context.Context, obj resource.Object) error {
+	cr, ok := obj.(*v1alpha1.VM)
+	if !ok {
+		return errors.New(errNotVM)
+	}
+
+	// Only ClusterProviderConfigs publish cluster connection secrets.
+	if ref := cr.GetProviderConfigReference(); ref != nil && ref.Kind == "ClusterProviderConfig" {
+		cpc := &apisv1alpha1.ClusterProviderConfig{}
+		if err := f.kube.Get(ctx, types.NamespacedName{Name: ref.Name}, cpc); resource.IgnoreNotFound(err) != nil {
+			return errors.Wrap(err, errGetCPC)
+		}
+		e := &external{kube: f.kube, clusterSecretNamespace: cpc.Spec.ConnectionSecretNamespace}
+		if err := e.unpublishClusterSecret(ctx, cr); err != nil {
+			return err
+		}
+	}
+	f.metrics.Delete(types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()})
+	return f.Finalizer.RemoveFinalizer(ctx, obj)
+}
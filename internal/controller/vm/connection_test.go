@@ -0,0 +1,321 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	sdk "github.com/slicervm/sdk"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/internal/metrics"
+)
+
+const testClusterSecretNamespace = "slicer-secrets"
+
+// secretStore is a Kubernetes client backed by an in-memory set of secrets.
+func secretStore(secrets map[types.NamespacedName]*corev1.Secret) *test.MockClient {
+	return &test.MockClient{
+		MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			s, ok := secrets[key]
+			if !ok {
+				return kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, key.Name)
+			}
+			s.DeepCopyInto(obj.(*corev1.Secret)) //nolint:forcetypeassert // Only secrets are read.
+			return nil
+		},
+		MockDelete: func(_ context.Context, obj client.Object, _ ...client.DeleteOption) error {
+			delete(secrets, client.ObjectKeyFromObject(obj))
+			return nil
+		},
+		MockList: test.NewMockListFn(nil),
+	}
+}
+
+func withConnectionSecret(name string) vmModifier {
+	return func(cr *v1alpha1.VM) {
+		cr.SetWriteConnectionSecretToReference(&xpv1.LocalSecretReference{Name: name})
+	}
+}
+
+// clusterSecret returns the cluster connection secret published for the VM
+// with the supplied UID.
+func clusterSecret(uid string) *corev1.Secret {
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace: testClusterSecretNamespace,
+		Name:      "default-conn",
+		Labels:    map[string]string{labelVMUID: uid},
+	}}
+}
+
+func TestClusterSecretDeleted(t *testing.T) {
+	key := types.NamespacedName{Namespace: testClusterSecretNamespace, Name: "default-conn"}
+
+	cases := map[string]struct {
+		reason string
+		api    slicerAPI
+		secret *corev1.Secret
+		run    func(ctx context.Context, e *external, cr *v1alpha1.VM) error
+		want   bool
+	}{
+		"Deleted": {
+			reason: "The cluster connection secret should be gone once the VM is deleted.",
+			api:    &fakeSlicer{},
+			secret: clusterSecret(testUID),
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				_, err := e.Delete(ctx, cr)
+				return err
+			},
+			want: false,
+		},
+		"DeletedOutOfBand": {
+			reason: "The cluster connection secret should be gone once a VM that was deleted out of band is observed being deleted.",
+			api:    &fakeSlicer{},
+			secret: clusterSecret(testUID),
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				withDeletionTimestamp()(cr)
+				_, err := e.Observe(ctx, cr)
+				return err
+			},
+			want: false,
+		},
+		"Orphaned": {
+			reason: "The cluster connection secret should be gone once a VM released to another owner is observed being deleted.",
+			api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: testHostname, Tags: []string{tagOwnerPrefix + "other"}}}, nil),
+			},
+			secret: clusterSecret(testUID),
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				withDeletionTimestamp()(cr)
+				_, err := e.Observe(ctx, cr)
+				return err
+			},
+			want: false,
+		},
+		"NotPublishedForVM": {
+			reason: "A secret that was not published for the VM should be kept.",
+			api:    &fakeSlicer{},
+			secret: clusterSecret("other"),
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				_, err := e.Delete(ctx, cr)
+				return err
+			},
+			want: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			secrets := map[types.NamespacedName]*corev1.Secret{key: tc.secret}
+			e := newTestExternal(tc.api, secretStore(secrets))
+			e.clusterSecretNamespace = testClusterSecretNamespace
+			cr := vm(withExternalName(testHostname), withConnectionSecret("conn"))
+
+			if err := tc.run(context.Background(), e, cr); err != nil {
+				t.Fatalf("\n%s\nunexpected error: %v", tc.reason, err)
+			}
+			_, got := secrets[key]
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\n-want secret exists, +got secret exists:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// Deleting the resource of a VM that is orphaned by its management policies,
+// or that was deleted out of band, must not delete a VM, and must remove the
+// resource and its cluster connection secret without error.
+func TestReconcileDeleted(t *testing.T) {
+	key := types.NamespacedName{Namespace: testClusterSecretNamespace, Name: "default-conn"}
+
+	type want struct {
+		deleted           bool
+		finalizerRemoved  bool
+		clusterSecretKept bool
+	}
+
+	cases := map[string]struct {
+		reason   string
+		policies xpv1.ManagementPolicies
+		nodes    []sdk.SlicerNode
+		want     want
+	}{
+		"Orphaned": {
+			reason:   "A VM whose management policies do not include Delete should be kept.",
+			policies: xpv1.ManagementPolicies{xpv1.ManagementActionObserve, xpv1.ManagementActionCreate, xpv1.ManagementActionUpdate, xpv1.ManagementActionLateInitialize},
+			nodes:    []sdk.SlicerNode{node()},
+			want:     want{finalizerRemoved: true},
+		},
+		"DeletedOutOfBand": {
+			reason:   "A VM that no longer exists should not be deleted again.",
+			policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll},
+			want:     want{finalizerRemoved: true},
+		},
+		"Exists": {
+			reason:   "A VM whose management policies include Delete should be deleted.",
+			policies: xpv1.ManagementPolicies{xpv1.ManagementActionAll},
+			nodes:    []sdk.SlicerNode{node()},
+			want:     want{deleted: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := vm(withExternalName(testHostname), withDeletionTimestamp(), withConnectionSecret("conn"))
+			cr.SetProviderConfigReference(&xpv1.ProviderConfigReference{Kind: "ClusterProviderConfig", Name: "default"})
+			cr.SetManagementPolicies(tc.policies)
+			cr.SetFinalizers([]string{managed.FinalizerName})
+
+			secrets := map[types.NamespacedName]*corev1.Secret{key: clusterSecret(testUID)}
+			store := secretStore(secrets)
+			kube := &test.MockClient{
+				MockGet: func(ctx context.Context, k client.ObjectKey, obj client.Object) error {
+					switch o := obj.(type) {
+					case *v1alpha1.VM:
+						cr.DeepCopyInto(o)
+						return nil
+					case *apisv1alpha1.ClusterProviderConfig:
+						o.Spec.ConnectionSecretNamespace = testClusterSecretNamespace
+						return nil
+					}
+					return store.MockGet(ctx, k, obj)
+				},
+				MockDelete:       store.MockDelete,
+				MockList:         store.MockList,
+				MockUpdate:       test.NewMockUpdateFn(nil),
+				MockPatch:        test.NewMockPatchFn(nil),
+				MockStatusUpdate: test.NewMockSubResourceUpdateFn(nil),
+			}
+
+			var deleted, finalizerRemoved bool
+			api := &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(tc.nodes, nil),
+				MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+					deleted = true
+					return &sdk.SlicerDeleteResponse{}, nil
+				},
+			}
+			e := newTestExternal(api, kube)
+			e.clusterSecretNamespace = testClusterSecretNamespace
+
+			s := runtime.NewScheme()
+			if err := v1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			r := managed.NewReconciler(&fake.Manager{Client: kube, Scheme: s}, resource.ManagedKind(v1alpha1.VMGroupVersionKind),
+				managed.WithManagementPolicies(),
+				managed.WithExternalConnector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+					return e, nil
+				})),
+				managed.WithFinalizer(&clusterSecretFinalizer{
+					Finalizer: resource.FinalizerFns{RemoveFinalizerFn: func(context.Context, resource.Object) error {
+						finalizerRemoved = true
+						return nil
+					}},
+					kube: kube,
+				}),
+			)
+
+			if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}}); err != nil {
+				t.Fatalf("\n%s\nr.Reconcile(...): unexpected error: %v", tc.reason, err)
+			}
+			_, kept := secrets[key]
+			got := want{deleted: deleted, finalizerRemoved: finalizerRemoved, clusterSecretKept: kept}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nr.Reconcile(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStateMetricsForgotten(t *testing.T) {
+	const noVMs = `
+# HELP slicervm_vms The number of VMs observed by the provider, by state.
+# TYPE slicervm_vms gauge
+slicervm_vms{state="available"} 0
+slicervm_vms{state="creating"} 0
+slicervm_vms{state="drifted"} 0
+slicervm_vms{state="unavailable"} 0
+`
+
+	cases := map[string]struct {
+		reason string
+		forget func(e *external, cr *v1alpha1.VM) error
+	}{
+		"Deleted": {
+			reason: "A deleted VM should no longer be counted.",
+			forget: func(e *external, cr *v1alpha1.VM) error {
+				_, err := e.Delete(context.Background(), cr)
+				return err
+			},
+		},
+		"Gone": {
+			reason: "A VM observed to no longer exist should no longer be counted.",
+			forget: func(e *external, cr *v1alpha1.VM) error {
+				e.client = &fakeSlicer{}
+				_, err := e.Observe(context.Background(), cr)
+				return err
+			},
+		},
+		"Orphaned": {
+			reason: "A VM whose resource is deleted without deleting the VM should no longer be counted.",
+			forget: func(e *external, cr *v1alpha1.VM) error {
+				f := &clusterSecretFinalizer{Finalizer: resource.NewNopFinalizer(), kube: e.kube, metrics: e.metrics}
+				return f.RemoveFinalizer(context.Background(), cr)
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			states := metrics.NewVMStates()
+			e := newTestExternal(&fakeSlicer{MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{node()}, nil)}, nil)
+			e.metrics = states
+			cr := vm(withExternalName(testHostname))
+			if _, err := e.Observe(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): unexpected error: %v", tc.reason, err)
+			}
+			if err := testutil.CollectAndCompare(states, strings.NewReader(noVMs)); err == nil {
+				t.Fatalf("\n%s\ne.Observe(...): VM is not counted", tc.reason)
+			}
+			if err := tc.forget(e, cr); err != nil {
+				t.Fatalf("\n%s\nunexpected error: %v", tc.reason, err)
+			}
+			if err := testutil.CollectAndCompare(states, strings.NewReader(noVMs)); err != nil {
+				t.Errorf("\n%s\n%v", tc.reason, err)
+			}
+		})
+	}
+}
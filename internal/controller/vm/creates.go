@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import "sync"
+
+// createSlots limits how many VMs are created at once in each host group, so
+// that VMs created together do not overwhelm the hypervisor backing a host
+// group. Host groups are keyed by API endpoint, credentials and host group.
+type createSlots struct {
+	mu       sync.Mutex
+	inFlight map[string]int
+}
+
+// newCreateSlots returns createSlots with no creates in flight.
+func newCreateSlots() *createSlots {
+	return &createSlots{inFlight: map[string]int{}}
+}
+
+// acquire takes a slot to create a VM in the host group of the supplied key,
+// if fewer than the supplied limit of creates are in flight in it. It returns
+// false if none is free. Taken slots must be released.
+func (s *createSlots) acquire(key string, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[key] >= limit {
+		return false
+	}
+	s.inFlight[key]++
+	return true
+}
+
+// release frees a slot taken by acquire.
+func (s *createSlots) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight[key]--; s.inFlight[key] <= 0 {
+		delete(s.inFlight, key)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCreateSlots(t *testing.T) {
+	const limit = 3
+	s := newCreateSlots()
+
+	var inFlight, most, acquired atomic.Int64
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !s.acquire("api", limit) {
+				return
+			}
+			acquired.Add(1)
+			n := inFlight.Add(1)
+			for {
+				m := most.Load()
+				if n <= m || most.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			inFlight.Add(-1)
+			s.release("api")
+		}()
+	}
+	wg.Wait()
+
+	if most.Load() > limit {
+		t.Errorf("s.acquire(...): %d creates were in flight, want at most %d", most.Load(), limit)
+	}
+	if acquired.Load() == 0 {
+		t.Error("s.acquire(...): no slot was ever acquired")
+	}
+	if diff := cmp.Diff(map[string]int{}, s.inFlight); diff != "" {
+		t.Errorf("s.release(...): -want no creates in flight, +got creates in flight:\n%s\n", diff)
+	}
+}
+
+func TestCreateSlotsLimit(t *testing.T) {
+	s := newCreateSlots()
+
+	for i := range 2 {
+		if !s.acquire("a/api", 2) {
+			t.Fatalf("s.acquire(...): slot %d was not free", i)
+		}
+	}
+	if s.acquire("a/api", 2) {
+		t.Error("s.acquire(...): acquired a slot beyond the limit")
+	}
+	if !s.acquire("b/api", 2) {
+		t.Error("s.acquire(...): host groups of other APIs should have their own slots")
+	}
+	s.release("a/api")
+	if !s.acquire("a/api", 2) {
+		t.Error("s.acquire(...): a released slot should be free")
+	}
+}
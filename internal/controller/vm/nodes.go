@@ -0,0 +1,154 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sync"
+	"time"
+
+	sdk "github.com/slicervm/sdk"
+	"golang.org/x/sync/singleflight"
+)
+
+// A lister coalesces listings of the Slicer API, so that VMs that reconcile
+// together share one Slicer API call. Listings are keyed by API endpoint and
+// credentials, so VMs of different provider configs never share them.
+type lister[T any] struct {
+	ttl   time.Duration
+	group singleflight.Group
+
+	mu       sync.Mutex
+	listings map[string]listing[T]
+
+	// generations counts how often each key was forgotten, so that a
+	// listing that started before it was forgotten is not cached.
+	generations map[string]uint64
+}
+
+// A listing is a successful listing of the Slicer API.
+type listing[T any] struct {
+	items []T
+	at    time.Time
+}
+
+// A nodeLister coalesces listings of the nodes of a host group. They are
+// keyed by host group as well as API endpoint and credentials.
+type nodeLister = lister[sdk.SlicerNode]
+
+// A statsLister coalesces listings of the stats of all VMs of an API
+// endpoint.
+type statsLister = lister[sdk.SlicerNodeStat]
+
+// newNodeLister returns a nodeLister that reuses successful listings for the
+// supplied TTL. Listings that are in flight are always shared.
+func newNodeLister(ttl time.Duration) *nodeLister {
+	return newLister[sdk.SlicerNode](ttl)
+}
+
+// newStatsLister returns a statsLister that reuses successful listings for
+// the supplied TTL. Listings that are in flight are always shared.
+func newStatsLister(ttl time.Duration) *statsLister {
+	return newLister[sdk.SlicerNodeStat](ttl)
+}
+
+// newLister returns a lister that reuses successful listings for the supplied
+// TTL.
+func newLister[T any](ttl time.Duration) *lister[T] {
+	return &lister[T]{ttl: ttl, listings: map[string]listing[T]{}, generations: map[string]uint64{}}
+}
+
+// apiID returns an identifier of the supplied API endpoint and token, which
+// prefixes the keys of the listings made with them.
+func apiID(url, token string) string {
+	h := sha256.Sum256([]byte(url + "\x00" + token))
+	return hex.EncodeToString(h[:])
+}
+
+// List returns the items listed under the supplied key. It calls list only if
+// no listing of the key is in flight or younger than the TTL. The listing is
+// not cancelled when the supplied context is, because other callers may be
+// waiting for it, so list must apply its own timeout.
+func (l *lister[T]) List(ctx context.Context, key string, list func(context.Context) ([]T, error)) ([]T, error) {
+	if l == nil {
+		return list(ctx)
+	}
+	if items, ok := l.cached(key); ok {
+		return items, nil
+	}
+
+	ch := l.group.DoChan(key, func() (any, error) {
+		l.mu.Lock()
+		gen := l.generations[key]
+		l.mu.Unlock()
+
+		items, err := list(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		if l.generations[key] == gen {
+			l.listings[key] = listing[T]{items: items, at: time.Now()}
+		}
+		l.mu.Unlock()
+		return items, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		if r.Err != nil {
+			return nil, r.Err
+		}
+		return slices.Clone(r.Val.([]T)), nil //nolint:forcetypeassert // Always a slice of T.
+	}
+}
+
+// Forget discards any listing of the supplied key, so that the next List
+// calls the API. A listing in flight is not cached when it completes, as it
+// may predate the change. Forget must be called after creating or deleting a
+// node.
+func (l *lister[T]) Forget(key string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	delete(l.listings, key)
+	l.generations[key]++
+	l.mu.Unlock()
+	l.group.Forget(key)
+}
+
+// cached returns the listing of the supplied key, if it is younger than the
+// TTL.
+func (l *lister[T]) cached(key string) ([]T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n, ok := l.listings[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(n.at) >= l.ttl {
+		delete(l.listings, key)
+		return nil, false
+	}
+	return slices.Clone(n.items), true
+}
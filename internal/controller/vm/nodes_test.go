@@ -0,0 +1,407 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	sdk "github.com/slicervm/sdk"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+// countingList returns a list function that counts its calls and returns the
+// supplied nodes.
+func countingList(calls *atomic.Int64, nodes ...sdk.SlicerNode) func(context.Context) ([]sdk.SlicerNode, error) {
+	return func(context.Context) ([]sdk.SlicerNode, error) {
+		calls.Add(1)
+		return nodes, nil
+	}
+}
+
+func TestNodeListerShared(t *testing.T) {
+	l := newNodeLister(time.Minute)
+	var calls atomic.Int64
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			nodes, err := l.List(context.Background(), "api", countingList(&calls, node()))
+			if err != nil {
+				t.Errorf("l.List(...): unexpected error: %v", err)
+				return
+			}
+			// Callers may modify their listing.
+			nodes[0].Hostname = "changed"
+		}()
+	}
+	wg.Wait()
+
+	if diff := cmp.Diff(int64(1), calls.Load()); diff != "" {
+		t.Errorf("l.List(...): -want calls, +got calls:\n%s\n", diff)
+	}
+	nodes, _ := l.List(context.Background(), "api", countingList(&calls))
+	if diff := cmp.Diff([]sdk.SlicerNode{node()}, nodes); diff != "" {
+		t.Errorf("l.List(...): -want cached listing, +got cached listing:\n%s\n", diff)
+	}
+}
+
+func TestNodeListerKeys(t *testing.T) {
+	l := newNodeLister(time.Minute)
+	var calls atomic.Int64
+
+	for _, key := range []string{"a/api", "b/api", "a/db", "a/api"} {
+		if _, err := l.List(context.Background(), key, countingList(&calls)); err != nil {
+			t.Fatalf("l.List(%q): unexpected error: %v", key, err)
+		}
+	}
+	if diff := cmp.Diff(int64(3), calls.Load()); diff != "" {
+		t.Errorf("l.List(...): -want calls, +got calls:\n%s\n", diff)
+	}
+}
+
+func TestNodeListerErrorsNotCached(t *testing.T) {
+	l := newNodeLister(time.Minute)
+	var calls atomic.Int64
+	fail := func(context.Context) ([]sdk.SlicerNode, error) {
+		calls.Add(1)
+		return nil, errBoom
+	}
+
+	for range 2 {
+		if _, err := l.List(context.Background(), "api", fail); err == nil {
+			t.Fatal("l.List(...): want error, got nil")
+		}
+	}
+	if diff := cmp.Diff(int64(2), calls.Load()); diff != "" {
+		t.Errorf("l.List(...): -want calls, +got calls:\n%s\n", diff)
+	}
+}
+
+// A VM created while its host group is being listed must not be reported
+// missing because the listing that started before it was created is reused.
+func TestNodeListerForgetDuringList(t *testing.T) {
+	l := newNodeLister(time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = l.List(context.Background(), "api", func(context.Context) ([]sdk.SlicerNode, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	}()
+
+	<-started
+	l.Forget("api")
+	close(release)
+	<-done
+
+	var calls atomic.Int64
+	nodes, err := l.List(context.Background(), "api", countingList(&calls, node()))
+	if err != nil {
+		t.Fatalf("l.List(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]sdk.SlicerNode{node()}, nodes); diff != "" {
+		t.Errorf("l.List(...): -want listing after create, +got listing after create:\n%s\n", diff)
+	}
+}
+
+func TestNodeListerCancelled(t *testing.T) {
+	l := newNodeLister(time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := l.List(ctx, "api", func(context.Context) ([]sdk.SlicerNode, error) {
+		<-release
+		return nil, nil
+	})
+	if diff := cmp.Diff(context.Canceled, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("l.List(...): -want error, +got error:\n%s\n", diff)
+	}
+}
+
+// The VMs of a host group observed while another is created share listings,
+// but the VM being created is never missing from the listing observed after
+// its create.
+func TestObserveAfterCreate(t *testing.T) {
+	var mu sync.Mutex
+	var listed []sdk.SlicerNode
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(context.Context, string) ([]sdk.SlicerNode, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]sdk.SlicerNode{}, listed...), nil
+		},
+		MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			listed = append(listed, node())
+			return &sdk.SlicerCreateNodeResponse{Hostname: testHostname, IP: testIP}, nil
+		},
+	}
+	lister := newNodeLister(time.Minute)
+	newExternal := func() *external {
+		e := newTestExternal(api, nil)
+		e.nodes = lister
+		return e
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = newExternal().Observe(context.Background(), vm(withExternalName("api-2")))
+		}()
+	}
+	cr := vm()
+	if _, err := newExternal().Create(context.Background(), cr); err != nil {
+		t.Fatalf("e.Create(...): unexpected error: %v", err)
+	}
+	wg.Wait()
+
+	obs, err := newExternal().Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): unexpected error: %v", err)
+	}
+	if !obs.ResourceExists {
+		t.Error("e.Observe(...): created VM is missing from the shared listing")
+	}
+}
+
+// benchmarkObserve observes the supplied number of VMs of a host group
+// concurrently, as a poll of many VMs would, with the node and stats listers
+// returned by the supplied function for each poll, and reports the listings
+// and stats calls per poll.
+func benchmarkObserve(b *testing.B, vms int, listers func() (*nodeLister, *statsLister)) {
+	b.Helper()
+
+	crs := make([]*v1alpha1.VM, vms)
+	nodes := make([]sdk.SlicerNode, vms)
+	stats := make([]sdk.SlicerNodeStat, vms)
+	for i := range vms {
+		hostname := fmt.Sprintf("api-%d", i+1)
+		crs[i] = vm(withExternalName(hostname))
+		crs[i].SetUID(types.UID(fmt.Sprintf("uid-%d", i)))
+		nodes[i] = sdk.SlicerNode{Hostname: hostname, Tags: []string{tagOwnerPrefix + string(crs[i].GetUID())}}
+		stats[i] = sdk.SlicerNodeStat{Hostname: hostname, Snapshot: &sdk.SlicerSnapshot{TotalCPUS: defaultCPUs, TotalMemory: defaultRAMGB << 30}}
+	}
+
+	var calls, statsCalls atomic.Int64
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(context.Context, string) ([]sdk.SlicerNode, error) {
+			calls.Add(1)
+			// Listing a host group takes a while.
+			time.Sleep(time.Millisecond)
+			return append([]sdk.SlicerNode{}, nodes...), nil
+		},
+		MockGetVMStats: func(_ context.Context, hostname string) ([]sdk.SlicerNodeStat, error) {
+			statsCalls.Add(1)
+			time.Sleep(time.Millisecond)
+			if hostname == "" {
+				return append([]sdk.SlicerNodeStat{}, stats...), nil
+			}
+			for _, st := range stats {
+				if st.Hostname == hostname {
+					return []sdk.SlicerNodeStat{st}, nil
+				}
+			}
+			return nil, nil
+		},
+	}
+
+	b.ResetTimer()
+	for range b.N {
+		nl, sl := listers()
+		var wg sync.WaitGroup
+		for _, cr := range crs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				e := newTestExternal(api, nil)
+				e.nodes, e.stats = nl, sl
+				if _, err := e.Observe(context.Background(), cr.DeepCopy()); err != nil {
+					b.Errorf("e.Observe(...): unexpected error: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	b.ReportMetric(float64(calls.Load())/float64(b.N), "lists/op")
+	b.ReportMetric(float64(statsCalls.Load())/float64(b.N), "stats/op")
+}
+
+// sharing returns the node and stats listers the provider uses when listings
+// are shared and reused for the supplied TTL.
+func sharing(ttl time.Duration) func() (*nodeLister, *statsLister) {
+	return func() (*nodeLister, *statsLister) { return newNodeLister(ttl), newStatsLister(ttl) }
+}
+
+// notSharing returns no listers, as the provider uses when sharing is
+// disabled.
+func notSharing() (*nodeLister, *statsLister) { return nil, nil }
+
+func BenchmarkObserve100VMs(b *testing.B) {
+	b.Run("Uncached", func(b *testing.B) {
+		benchmarkObserve(b, 100, notSharing)
+	})
+	b.Run("Cached", func(b *testing.B) {
+		benchmarkObserve(b, 100, sharing(time.Minute))
+	})
+}
+
+func BenchmarkObserve200VMs(b *testing.B) {
+	b.Run("SharingDisabled", func(b *testing.B) {
+		benchmarkObserve(b, 200, notSharing)
+	})
+	b.Run("ConcurrentOnly", func(b *testing.B) {
+		benchmarkObserve(b, 200, sharing(0))
+	})
+	b.Run("TTL", func(b *testing.B) {
+		benchmarkObserve(b, 200, sharing(time.Minute))
+	})
+}
+
+func BenchmarkObserve500VMs(b *testing.B) {
+	b.Run("SharingDisabled", func(b *testing.B) {
+		benchmarkObserve(b, 500, notSharing)
+	})
+	b.Run("TTL", func(b *testing.B) {
+		benchmarkObserve(b, 500, sharing(time.Minute))
+	})
+}
+
+// Slicer lists all nodes of a host group in one unpaginated response, so a VM
+// anywhere in a listing of a large host group is found with one call.
+func TestObserveLargeHostGroup(t *testing.T) {
+	nodes := make([]sdk.SlicerNode, 500)
+	for i := range nodes {
+		nodes[i] = node()
+		nodes[i].Hostname = fmt.Sprintf("api-%d", i+1)
+	}
+	var calls atomic.Int64
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(context.Context, string) ([]sdk.SlicerNode, error) {
+			calls.Add(1)
+			return append([]sdk.SlicerNode{}, nodes...), nil
+		},
+	}
+
+	for _, hostname := range []string{"api-1", "api-250", "api-500"} {
+		calls.Store(0)
+		cr := vm(withExternalName(hostname))
+		obs, err := newTestExternal(api, nil).Observe(context.Background(), cr)
+		if err != nil {
+			t.Fatalf("e.Observe(%q): unexpected error: %v", hostname, err)
+		}
+		if !obs.ResourceExists {
+			t.Errorf("e.Observe(%q): VM is missing from a listing of 500 nodes", hostname)
+		}
+		if diff := cmp.Diff(hostname, cr.Status.AtProvider.Hostname); diff != "" {
+			t.Errorf("e.Observe(%q): -want hostname, +got hostname:\n%s\n", hostname, diff)
+		}
+		if diff := cmp.Diff(int64(1), calls.Load()); diff != "" {
+			t.Errorf("e.Observe(%q): -want listings, +got listings:\n%s\n", hostname, diff)
+		}
+	}
+}
+
+// A VM deleted by one reconcile must not be reported to exist by the next
+// because the listing made before it was deleted is reused.
+func TestObserveAfterDelete(t *testing.T) {
+	listed := []sdk.SlicerNode{node()}
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(context.Context, string) ([]sdk.SlicerNode, error) {
+			return append([]sdk.SlicerNode{}, listed...), nil
+		},
+		MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+			listed = nil
+			return &sdk.SlicerDeleteResponse{}, nil
+		},
+	}
+	e := newTestExternal(api, nil)
+	e.nodes = newNodeLister(time.Minute)
+	cr := vm(withExternalName(testHostname))
+
+	if obs, err := e.Observe(context.Background(), cr); err != nil || !obs.ResourceExists {
+		t.Fatalf("e.Observe(...): want existing VM, got %+v, %v", obs, err)
+	}
+	if _, err := e.Delete(context.Background(), cr); err != nil {
+		t.Fatalf("e.Delete(...): unexpected error: %v", err)
+	}
+	obs, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): unexpected error: %v", err)
+	}
+	if obs.ResourceExists {
+		t.Error("e.Observe(...): deleted VM is reported from the shared listing")
+	}
+}
+
+// VMs observed together share one listing of the stats of all VMs, but a VM
+// missing from the listing reads its own stats.
+func TestObserveSharedStats(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(_ context.Context, _ string) ([]sdk.SlicerNode, error) {
+			nodes := []sdk.SlicerNode{node(), node(), node()}
+			nodes[1].Hostname, nodes[2].Hostname = "api-2", "api-3"
+			return nodes, nil
+		},
+		MockGetVMStats: func(_ context.Context, hostname string) ([]sdk.SlicerNodeStat, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, hostname)
+			if hostname == "" {
+				return []sdk.SlicerNodeStat{stats(2, 4)[0], {Hostname: "api-2", Snapshot: &sdk.SlicerSnapshot{TotalCPUS: 2}}}, nil
+			}
+			return []sdk.SlicerNodeStat{{Hostname: hostname, Snapshot: &sdk.SlicerSnapshot{TotalCPUS: 8}}}, nil
+		},
+	}
+	l := newStatsLister(time.Minute)
+	want := map[string]int{testHostname: 2, "api-2": 2, "api-3": 8}
+	for hostname, cpus := range want {
+		e := newTestExternal(api, nil)
+		e.stats = l
+		cr := vm(withExternalName(hostname))
+		if _, err := e.Observe(context.Background(), cr); err != nil {
+			t.Fatalf("e.Observe(%q): unexpected error: %v", hostname, err)
+		}
+		if diff := cmp.Diff(cpus, cr.Status.AtProvider.CPUs); diff != "" {
+			t.Errorf("e.Observe(%q): -want CPUs, +got CPUs:\n%s\n", hostname, diff)
+		}
+	}
+	if diff := cmp.Diff([]string{"", "api-3"}, calls, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("e.Observe(...): -want stats calls, +got stats calls:\n%s\n", diff)
+	}
+}
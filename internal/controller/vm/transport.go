@@ -0,0 +1,430 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	sdk "github.com/slicervm/sdk"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+// headerIdempotencyKey is the header carrying the idempotency key of create
+// node requests.
+const headerIdempotencyKey = "Idempotency-Key"
+
+// retryBackoff is the backoff before the first retry of a failed request. It
+// doubles with every retry.
+const retryBackoff = 250 * time.Millisecond
+
+// createNodeFields are the JSON fields of the create request that are modeled
+// by the SDK and therefore cannot be supplied as extra fields. The SDK's VM
+// create request names the same fields differently, for example sshKeys
+// rather than ssh_keys, so those names are included too.
+var createNodeFields = modeledFields(reflect.TypeOf(sdk.SlicerCreateNodeRequest{}), reflect.TypeOf(sdk.SlicerCreateVMRequest{}))
+
+// modeledFields returns the JSON field names of the supplied node create
+// request type, and of the fields of the supplied VM create request type that
+// it also has.
+func modeledFields(node, vm reflect.Type) map[string]bool {
+	fields := map[string]bool{}
+	add := func(f reflect.StructField) {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	for i := range node.NumField() {
+		add(node.Field(i))
+	}
+	for i := range vm.NumField() {
+		if _, ok := node.FieldByName(vm.Field(i).Name); ok {
+			add(vm.Field(i))
+		}
+	}
+	return fields
+}
+
+// validateExtraFields returns an error if any of the extra fields of the
+// supplied parameters conflict with a field the provider already models.
+func validateExtraFields(p v1alpha1.VMParameters) error {
+	var conflicts []string
+	for k := range p.ExtraFields {
+		if createNodeFields[k] {
+			conflicts = append(conflicts, k)
+		}
+	}
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return errors.Errorf("extra fields conflict with modeled create request fields: %s", strings.Join(conflicts, ", "))
+	}
+	return nil
+}
+
+// extraFieldsTransport merges additional fields into the JSON body of create
+// node requests, so that API fields the SDK does not model yet can be sent.
+type extraFieldsTransport struct {
+	base   http.RoundTripper
+	fields map[string]json.RawMessage
+}
+
+// RoundTrip merges the extra fields into create node requests before sending
+// them using the base transport.
+func (t *extraFieldsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isCreateNode(req) || req.Body == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	raw, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read request body")
+	}
+
+	body := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, errors.Wrap(err, "cannot decode request body")
+	}
+	for k, v := range t.fields {
+		if _, ok := body[k]; !ok {
+			body[k] = v
+		}
+	}
+	merged, err := json.Marshal(body)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot encode request body")
+	}
+
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(merged))
+	r.ContentLength = int64(len(merged))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(merged)), nil }
+	return t.base.RoundTrip(r)
+}
+
+// idempotencyTransport sets an idempotency key on create node requests, so
+// that the Slicer API can recognise a retried create of the same VM instead of
+// creating a duplicate.
+type idempotencyTransport struct {
+	base http.RoundTripper
+	key  string
+}
+
+// RoundTrip sets the idempotency key on create node requests before sending
+// them using the base transport.
+func (t *idempotencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isCreateNode(req) {
+		return t.base.RoundTrip(req)
+	}
+	r := req.Clone(req.Context())
+	r.Header.Set(headerIdempotencyKey, t.key)
+	return t.base.RoundTrip(r)
+}
+
+// maxErrorBodyBytes is how much of the body of an error response is kept.
+const maxErrorBodyBytes = 4 << 10
+
+// An apiError is an error response of the Slicer API.
+type apiError struct {
+	StatusCode int
+	Body       string
+}
+
+// Error returns the status and body of the error response.
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Slicer API responded %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Body)
+}
+
+// isNotFound returns true if the supplied error is a not found response of the
+// Slicer API.
+func isNotFound(err error) bool {
+	var ae *apiError
+	return errors.As(err, &ae) && ae.StatusCode == http.StatusNotFound
+}
+
+// errorRecorder records the error response of the last request it sent. The
+// SDK only reports error responses as formatted strings, so this lets callers
+// recover the status code and body.
+type errorRecorder struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	last *apiError
+}
+
+// RoundTrip sends the supplied request using the base transport, recording
+// the response if it is an error.
+func (t *errorRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.set(nil)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot read error response body")
+	}
+	t.set(&apiError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+func (t *errorRecorder) set(e *apiError) {
+	t.mu.Lock()
+	t.last = e
+	t.mu.Unlock()
+}
+
+// wrap returns the error response of the last request if the supplied error
+// was caused by one, and the supplied error otherwise.
+func (t *errorRecorder) wrap(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil || t.last == nil {
+		return err
+	}
+	return t.last
+}
+
+// retryTransport retries GET requests that fail with a connection error or a
+// server error. Other requests may not be idempotent, so they are never
+// retried.
+type retryTransport struct {
+	base       http.RoundTripper
+	maxRetries int
+}
+
+// RoundTrip sends the supplied request using the base transport, retrying
+// failed GET requests with exponential backoff.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.maxRetries || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(retryBackoff << attempt):
+		}
+	}
+}
+
+// defaultRetryAfter is how long requests to a Slicer API that responded 429 Too
+// Many Requests without a Retry-After header are held back.
+const defaultRetryAfter = 5 * time.Second
+
+// A rateLimitedError is returned for requests that were not sent because the
+// Slicer API asked clients to back off.
+type rateLimitedError struct {
+	until time.Time
+}
+
+// Error returns when requests are sent again.
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("Slicer API is rate limiting requests, retrying after %s", e.until.Format(time.RFC3339))
+}
+
+// throttles tracks the Slicer APIs that responded 429 Too Many Requests, so
+// that all VMs using an API back off together rather than adding to the
+// load that caused it to rate limit them. APIs are keyed by endpoint and
+// credentials.
+type throttles struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+// newThrottles returns throttles that hold back no API.
+func newThrottles() *throttles {
+	return &throttles{until: map[string]time.Time{}}
+}
+
+// blocked returns when requests to the supplied API may be sent again, if
+// they are held back.
+func (t *throttles) blocked(api string) (time.Time, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	until, ok := t.until[api]
+	if ok && !time.Now().Before(until) {
+		delete(t.until, api)
+		return time.Time{}, false
+	}
+	return until, ok
+}
+
+// hold holds back requests to the supplied API until the supplied time.
+func (t *throttles) hold(api string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.until[api]) {
+		t.until[api] = until
+	}
+}
+
+// throttleTransport holds back requests to a Slicer API that responded 429
+// Too Many Requests until the time its Retry-After header asked for.
+type throttleTransport struct {
+	base      http.RoundTripper
+	throttles *throttles
+	api       string
+}
+
+// RoundTrip sends the supplied request using the base transport, unless the
+// API is being backed off from.
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if until, ok := t.throttles.blocked(t.api); ok {
+		return nil, &rateLimitedError{until: until}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.throttles.hold(t.api, time.Now().Add(retryAfter(resp.Header.Get("Retry-After"))))
+	}
+	return resp, err
+}
+
+// retryAfter returns how long to wait according to the supplied Retry-After
+// header, which holds either a number of seconds or an HTTP date.
+func retryAfter(h string) time.Duration {
+	if s, err := strconv.Atoi(h); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return defaultRetryAfter
+}
+
+// isRateLimited returns true if the supplied error was caused by the Slicer
+// API rate limiting requests.
+func isRateLimited(err error) bool {
+	var ae *apiError
+	var rle *rateLimitedError
+	return errors.As(err, &rle) || (errors.As(err, &ae) && ae.StatusCode == http.StatusTooManyRequests)
+}
+
+// isCreateNode returns true if the supplied request creates a node.
+func isCreateNode(req *http.Request) bool {
+	return req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/nodes")
+}
+
+// idempotencyKey returns the idempotency key used to create the supplied VM.
+// It is derived from the resource's UID, so it is stable across retries.
+func idempotencyKey(cr *v1alpha1.VM) string {
+	return "provider-slicervm-" + string(cr.GetUID())
+}
+
+// tlsConfigFor returns the TLS config verifying the Slicer API's certificate as
+// configured by the supplied options. CA bundles are trusted in addition to
+// the system's CAs. A CA bundle secret is read from the supplied namespace,
+// ignoring the namespace of its reference, so that a namespaced provider
+// config cannot read secrets of other namespaces. An empty namespace uses the
+// namespace of the reference.
+func tlsConfigFor(ctx context.Context, kube client.Client, o *apisv1alpha1.TLSOptions, namespace string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: o.InsecureSkipVerify} //nolint:gosec // Explicitly requested, and warned about.
+
+	bundle := o.CABundle
+	if ref := o.CABundleSecretRef; ref != nil {
+		ns := namespace
+		if ns == "" {
+			ns = ref.Namespace
+		}
+		s := &corev1.Secret{}
+		if err := kube.Get(ctx, types.NamespacedName{Namespace: ns, Name: ref.Name}, s); err != nil {
+			return nil, errors.Wrap(err, errGetCABundle)
+		}
+		b, ok := s.Data[ref.Key]
+		if !ok {
+			return nil, errors.Errorf(errMissingCABundleKey, ns, ref.Name, ref.Key)
+		}
+		bundle += "\n" + string(b)
+	}
+	if strings.TrimSpace(bundle) == "" {
+		return cfg, nil
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM([]byte(bundle)) {
+		return nil, errors.New(errInvalidCABundle)
+	}
+	cfg.RootCAs = pool
+	return cfg, nil
+}
+
+// transportFor returns the transport that connects to the Slicer API of the
+// supplied provider config. It is the shared default transport, unless the
+// provider config needs its own TLS config.
+func transportFor(cfg slicerConfig) *http.Transport {
+	def := http.DefaultTransport.(*http.Transport) //nolint:forcetypeassert // The default transport is an *http.Transport.
+	if cfg.TLSConfig == nil {
+		return def
+	}
+	t := def.Clone()
+	t.TLSClientConfig = cfg.TLSConfig
+	return t
+}
+
+// httpClientFor returns the HTTP client to use for the supplied VM's API
+// calls, configured by the supplied provider config, sending requests using
+// the supplied transport and backing off from rate limiting APIs using the
+// supplied throttles, and the recorder of the error responses it receives.
+func httpClientFor(cr *v1alpha1.VM, cfg slicerConfig, base *http.Transport, th *throttles) (*http.Client, *errorRecorder) {
+	var t http.RoundTripper = &throttleTransport{base: base, throttles: th, api: apiID(cfg.URL, cfg.Token)}
+	if cfg.MaxRetries > 0 {
+		t = &retryTransport{base: t, maxRetries: cfg.MaxRetries}
+	}
+	fields := make(map[string]json.RawMessage, len(cr.Spec.ForProvider.ExtraFields))
+	for k, v := range cr.Spec.ForProvider.ExtraFields {
+		fields[k] = json.RawMessage(v.Raw)
+	}
+	if len(fields) > 0 {
+		t = &extraFieldsTransport{base: t, fields: fields}
+	}
+	t = &idempotencyTransport{base: t, key: idempotencyKey(cr)}
+	rec := &errorRecorder{base: t}
+	return &http.Client{Transport: rec, Timeout: durationOr(cfg.Timeouts.Request, 0)}, rec
+}
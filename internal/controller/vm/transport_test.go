@@ -0,0 +1,305 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	sdk "github.com/slicervm/sdk"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+// newServerExternal returns an external for the supplied VM that calls the
+// Slicer API served by the supplied server, using the client the connector
+// would build for the supplied config.
+func newServerExternal(srv *httptest.Server, cr *v1alpha1.VM, cfg slicerConfig) *external {
+	cfg.URL, cfg.Token = srv.URL, "token"
+	transport := transportFor(cfg)
+	hc, apiErrors := httpClientFor(cr, cfg, transport, newThrottles())
+	e := newTestExternal(sdk.NewSlicerClient(cfg.URL, cfg.Token, userAgentFor(cr), hc), nil)
+	e.apiErrors = apiErrors
+	e.transport = transport
+	return e
+}
+
+func TestCreateErrorResponse(t *testing.T) {
+	const detail = `{"error":"ram_gb: 512 exceeds the host group's maximum of 64"}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = w.Write([]byte(detail))
+	}))
+	defer srv.Close()
+
+	cr := vm(withSize(2, 512))
+	e := newServerExternal(srv, cr, slicerConfig{})
+	_, err := e.Create(context.Background(), cr)
+	if err == nil {
+		t.Fatal("e.Create(...): want error, got nil")
+	}
+	for _, want := range []string{"422", detail} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("e.Create(...): error %q does not contain %q", err, want)
+		}
+	}
+	if diff := cmp.Diff(err.Error(), cr.Status.AtProvider.LastCreateError); diff != "" {
+		t.Errorf("e.Create(...): -want last create error, +got last create error:\n%s\n", diff)
+	}
+}
+
+func TestSlowServer(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	type want struct {
+		attempts int
+	}
+
+	cases := map[string]struct {
+		reason string
+		cfg    slicerConfig
+		run    func(ctx context.Context, e *external, cr *v1alpha1.VM) error
+		want   want
+	}{
+		"Observe": {
+			reason: "A listing that does not complete within the observe timeout should fail with a timeout.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				e.observeTimeout = timeout
+				withExternalName(testHostname)(cr)
+				_, err := e.Observe(ctx, cr)
+				return err
+			},
+		},
+		"Create": {
+			reason: "A create that does not complete within the create timeout should fail with a timeout, and not count as a failed attempt.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				e.createTimeout, e.observeTimeout = timeout, time.Minute
+				_, err := e.Create(ctx, cr)
+				return err
+			},
+		},
+		"Delete": {
+			reason: "A delete that does not complete within the delete timeout should fail with a timeout.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				e.deleteTimeout = timeout
+				withExternalName(testHostname)(cr)
+				_, err := e.Delete(ctx, cr)
+				return err
+			},
+		},
+		"RequestTimeout": {
+			reason: "A request that does not complete within the request timeout should fail with a timeout.",
+			cfg:    slicerConfig{Timeouts: apisv1alpha1.OperationTimeouts{Request: &metav1.Duration{Duration: timeout}}},
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) error {
+				withExternalName(testHostname)(cr)
+				_, err := e.Observe(ctx, cr)
+				return err
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			// Creates are only slow once the listing that precedes them
+			// succeeded.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if name == "Create" && r.Method == http.MethodGet {
+					_, _ = w.Write([]byte("[]"))
+					return
+				}
+				// The server only notices the client going away once the
+				// request body was read.
+				_, _ = io.Copy(io.Discard, r.Body)
+				select {
+				case <-r.Context().Done():
+				case <-time.After(10 * time.Second):
+				}
+			}))
+			defer srv.Close()
+
+			cr := vm()
+			e := newServerExternal(srv, cr, tc.cfg)
+			start := time.Now()
+			err := tc.run(context.Background(), e, cr)
+			if !isTimeout(err) {
+				t.Errorf("\n%s\nwant a timeout error, got %v", tc.reason, err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("\n%s\nthe call took %s, want it cut short by its deadline", tc.reason, elapsed)
+			}
+			if diff := cmp.Diff(tc.want.attempts, cr.Status.AtProvider.CreateAttempts); diff != "" {
+				t.Errorf("\n%s\n-want create attempts, +got create attempts:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cfg    slicerConfig
+		want   bool
+	}{
+		"OwnTransport": {
+			reason: "The idle connections of a transport made for the connection should be closed.",
+			cfg:    slicerConfig{TLSConfig: &tls.Config{MinVersion: tls.VersionTLS12}},
+			want:   true,
+		},
+		"DefaultTransport": {
+			reason: "The idle connections of the shared default transport should be kept for later reconciles.",
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			closed := make(chan struct{})
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("[]"))
+			}))
+			srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+				if s == http.StateClosed {
+					close(closed)
+				}
+			}
+			srv.Start()
+			defer srv.Close()
+
+			cr := vm(withExternalName(testHostname))
+			e := newServerExternal(srv, cr, tc.cfg)
+			if _, err := e.Observe(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): unexpected error: %v", tc.reason, err)
+			}
+			if err := e.Disconnect(context.Background()); err != nil {
+				t.Fatalf("\n%s\ne.Disconnect(...): unexpected error: %v", tc.reason, err)
+			}
+
+			var got bool
+			select {
+			case <-closed:
+				got = true
+			case <-time.After(200 * time.Millisecond):
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.Disconnect(...): -want connection closed, +got connection closed:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateExtraFields(t *testing.T) {
+	extra := func(names ...string) map[string]extv1.JSON {
+		fields := make(map[string]extv1.JSON, len(names))
+		for _, n := range names {
+			fields[n] = extv1.JSON{Raw: []byte(`"x"`)}
+		}
+		return fields
+	}
+
+	cases := map[string]struct {
+		reason string
+		p      v1alpha1.VMParameters
+		want   error
+	}{
+		"Unmodeled": {
+			reason: "Fields the provider does not model should be allowed.",
+			p:      v1alpha1.VMParameters{ExtraFields: extra("diskImage", "persistent")},
+		},
+		"NodeRequestField": {
+			reason: "Fields of the node create request should be rejected.",
+			p:      v1alpha1.VMParameters{ExtraFields: extra("ssh_keys", "import_user")},
+			want:   errors.New("extra fields conflict with modeled create request fields: import_user, ssh_keys"),
+		},
+		"VMRequestField": {
+			reason: "The names the SDK's VM create request uses for modeled fields should be rejected.",
+			p:      v1alpha1.VMParameters{ExtraFields: extra("sshKeys", "importUser", "userdata")},
+			want:   errors.New("extra fields conflict with modeled create request fields: importUser, sshKeys, userdata"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateExtraFields(tc.p)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nvalidateExtraFields(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTLSConfigForSecretNamespace(t *testing.T) {
+	ref := &xpv1.SecretKeySelector{
+		SecretReference: xpv1.SecretReference{Namespace: "other", Name: "ca"},
+		Key:             "ca.crt",
+	}
+
+	cases := map[string]struct {
+		reason    string
+		namespace string
+		wantRead  client.ObjectKey
+		want      error
+	}{
+		"Namespaced": {
+			reason:    "The CA bundle secret of a namespaced provider config should be read from its own namespace.",
+			namespace: "team",
+			wantRead:  client.ObjectKey{Namespace: "team", Name: "ca"},
+			want:      errors.Errorf(errMissingCABundleKey, "team", "ca", "ca.crt"),
+		},
+		"Cluster": {
+			reason:   "The CA bundle secret of a cluster provider config should be read from the referenced namespace.",
+			wantRead: client.ObjectKey{Namespace: "other", Name: "ca"},
+			want:     errors.Errorf(errMissingCABundleKey, "other", "ca", "ca.crt"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var read client.ObjectKey
+			kube := &test.MockClient{MockGet: func(_ context.Context, key client.ObjectKey, _ client.Object) error {
+				read = key
+				return nil
+			}}
+			_, err := tlsConfigFor(context.Background(), kube, &apisv1alpha1.TLSOptions{CABundleSecretRef: ref}, tc.namespace)
+			if diff := cmp.Diff(tc.want, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ntlsConfigFor(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.wantRead, read); diff != "" {
+				t.Errorf("\n%s\ntlsConfigFor(...): -want secret read, +got secret read:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
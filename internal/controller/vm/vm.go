@@ -18,11 +18,26 @@ package vm
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
@@ -30,27 +45,217 @@ import (
 	"github.com/crossplane/crossplane-runtime/v2/pkg/statemetrics"
 	"github.com/pkg/errors"
 	sdk "github.com/slicervm/sdk"
+	corev1 "k8s.io/api/core/v1"
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
 	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/internal/features"
+	"github.com/gaarutyunov/provider-slicervm/internal/metrics"
 )
 
 const (
-	errNotVM        = "managed resource is not a VM custom resource"
-	errTrackPCUsage = "cannot track ProviderConfig usage"
-	errGetPC        = "cannot get ProviderConfig"
-	errGetCPC       = "cannot get ClusterProviderConfig"
-	errGetCreds     = "cannot get credentials"
-	errNewClient    = "cannot create new Slicer client"
+	errNotVM              = "managed resource is not a VM custom resource"
+	errTrackPCUsage       = "cannot track ProviderConfig usage"
+	errGetPC              = "cannot get ProviderConfig"
+	errGetCPC             = "cannot get ClusterProviderConfig"
+	errEndpointNotAllowed = "endpoint override %q is not allowed by the provider config's allowedEndpointOverrides"
+	errGetCreds           = "cannot get credentials"
+	errCredsPending       = "credentials secret does not exist yet"
+	errEmptyToken         = "credentials source %s yielded an empty Slicer API token"
+	errGetCABundle        = "cannot get CA bundle secret"
+	errMissingCABundleKey = "CA bundle secret %s/%s has no key %q"
+	errInvalidCABundle    = "CA bundle contains no PEM encoded certificates"
+	errNewClient          = "cannot create new Slicer client"
+	errRebootVM           = "cannot reboot VM"
+	errRecreateVM         = "cannot delete VM for recreation"
+	errDeleteExpired      = "cannot delete expired VM"
+	errCreateTimeout      = "cannot create VM: Slicer API did not respond within %s, retrying"
+	errShutdownVM         = "cannot shut down VM, deleting it without shutting down"
+	errShutdownTimeout    = "VM did not shut down within %s, deleting it anyway"
+	errCloudInitFailed    = "cloud-init failed"
+	errTooManyCreates     = "%d VMs are already being created in host group %q, retrying"
+	errCloudInitTimeout   = "cloud-init did not finish within %s of the VM being created"
+
+	errRecreateRequired = "parameters that can only be set on creation have changed: the VM must be recreated for them to take effect"
+
+	errForeignVM         = "refusing to adopt VM %q: it is not owned by this resource"
+	errListTagPolicies   = "cannot list tag policies"
+	errMissingTags       = "missing tags required by tag policies: %s"
+	errHostGroupNotFound = "host group %q does not exist in the Slicer API: set spec.forProvider.hostGroup, or the provider config's hostGroup, to an existing host group"
+	errListCandidate     = "cannot list VMs of candidate host group %q"
+	errHostGroupsFull    = "all candidate host groups (%s) have %d or more VMs"
+	errHostGroupChanged  = "cannot move VM from host group %q to %q: VMs cannot be migrated between host groups, delete and recreate the VM instead"
+
+	errExceedsMaxCPUs  = "requested %d CPUs exceed the host limit of %d"
+	errExceedsMaxRAMGB = "requested %d GB of RAM exceeds the host limit of %d GB"
+
+	errGetUserdataSecret           = "cannot get userdata secret %s"
+	errMissingUserdataSecretKey    = "userdata secret %s has no key %q"
+	errGetUserdataConfigMap        = "cannot get userdata config map %s"
+	errMissingUserdataConfigMapKey = "userdata config map %s has no key %q"
+	errGetSSHKeySecret             = "cannot get SSH key secret %s"
+	errMissingSSHKey               = "SSH key secret %s has no key %q"
+	errTagsDrifted                 = "VM tags cannot be updated in place and differ from the desired tags: %s"
+	errCreateGaveUp                = "gave up creating the VM after failing for %s; retrying after %s"
+)
+
+const (
+	// userAgent identifies the provider to the Slicer API.
+	userAgent = "provider-slicervm/1.0"
+
+	// annotationCorrelationID is an optional VM annotation whose value is
+	// appended to the user-agent of every Slicer API call made for that VM, so
+	// provider actions can be correlated with Slicer server logs.
+	annotationCorrelationID = "slicervm.crossplane.io/correlation-id"
+
+	// annotationReboot triggers a reboot of the VM whenever its value changes.
+	// The VM is rebooted in place, not recreated.
+	annotationReboot = "slicervm.crossplane.io/reboot"
+
+	// annotationPollInterval requests a different poll interval for an
+	// Available VM, for VMs that need tighter or looser monitoring.
+	annotationPollInterval = v1alpha1.AnnotationPollInterval
+
+	// annotationAdopt allows a VM resource to adopt the existing Slicer VM
+	// its external name refers to, if that VM has no owner tag.
+	annotationAdopt = "slicervm.crossplane.io/adopt"
+
+	// annotationDryRun keeps a VM from being created when set to "true".
+	// Instead, its parameters are validated.
+	annotationDryRun = "slicervm.crossplane.io/dry-run"
+
+	// tagOwnerPrefix prefixes the tag that marks a Slicer VM as owned by a VM
+	// resource. The tag's value is the UID of the resource.
+	tagOwnerPrefix = "slicervm.crossplane.io/owner="
+
+	// tagK8sName and tagK8sNamespace are the keys of the metadata tags
+	// naming a VM's resource.
+	tagK8sName      = "k8s-name"
+	tagK8sNamespace = "k8s-namespace"
+)
+
+// msgDryRun is the message of the Ready condition of VMs in dry-run mode.
+const msgDryRun = "VM is in dry-run mode and is not created; see the DryRun condition"
+
+// Default size of VMs that do not specify one.
+const (
+	defaultCPUs  = 2
+	defaultRAMGB = 4
+)
+
+// Default timeouts of Slicer API calls.
+const (
+	defaultCreateTimeout  = 45 * time.Second
+	defaultDeleteTimeout  = 30 * time.Second
+	defaultObserveTimeout = 15 * time.Second
+	defaultUpdateTimeout  = 30 * time.Second
+
+	defaultShutdownTimeout  = time.Minute
+	defaultCloudInitTimeout = 10 * time.Minute
+)
+
+// Default bounds of the per-VM backoff of failing reconciles, matching
+// crossplane-runtime's controller rate limiter.
+const (
+	defaultBackoffBase = time.Second
+	defaultBackoffMax  = time.Minute
+)
+
+// Defaults and tolerances of restart detection.
+const (
+	defaultRestartThreshold = 3
+	defaultRestartWindow    = time.Hour
+
+	// bootTimeTolerance absorbs jitter in boot times derived from the uptime
+	// reported in successive stats.
+	bootTimeTolerance = 30 * time.Second
+)
+
+// probeTimeout bounds how long a reachability probe may take.
+const probeTimeout = 2 * time.Second
+
+// Event reasons. Failures are recorded as events by the managed reconciler.
+const (
+	reasonCreated          event.Reason = "CreatedVM"
+	reasonAvailable        event.Reason = "VMAvailable"
+	reasonRebooted         event.Reason = "RebootedVM"
+	reasonDeleted          event.Reason = "DeletedVM"
+	reasonShuttingDown     event.Reason = "ShuttingDownVM"
+	reasonShutdownFailed   event.Reason = "ShutdownFailed"
+	reasonRecreateRequired event.Reason = "RecreateRequired"
+	reasonRecreating       event.Reason = "RecreatingVM"
+	reasonCreateGaveUp     event.Reason = "GaveUpCreatingVM"
+	reasonTagsDrifted      event.Reason = "TagsDrifted"
+)
+
+// VM states reported in the VM's status.
+const (
+	stateStarting = "starting"
+	stateRunning  = "running"
 )
 
+// Options configures the VM controller beyond the common controller options.
+type Options struct {
+	// MinPollInterval is the shortest poll interval a VM may request using
+	// the poll interval annotation.
+	MinPollInterval time.Duration
+
+	// StateMetrics counts the observed VMs by state, if non-nil.
+	StateMetrics *metrics.VMStates
+
+	// APIMetrics times the Slicer API calls, if non-nil.
+	APIMetrics *metrics.APICalls
+
+	// CreationGracePeriod is how long after a successful create a VM that is
+	// not listed yet is assumed to still be provisioning, rather than
+	// missing, to tolerate lag in the Slicer API's node listing. Zero
+	// disables it, so a VM that is not listed is created again right away.
+	CreationGracePeriod time.Duration
+
+	// BackoffBase and BackoffMax bound the exponential backoff applied to a
+	// VM whose reconciles keep failing. The backoff is tracked per VM, so one
+	// failing VM does not slow down the others. Zero keeps the defaults.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// MaxConcurrentReconciles overrides the common maximum number of
+	// concurrent reconciles for the VM controller, if non-zero.
+	MaxConcurrentReconciles int
+
+	// CreateRetryLimit is how long failed attempts to create a VM are
+	// retried before the provider gives up, if non-zero. Once given up, no
+	// create is attempted until CreateRetryCooldown has passed, after which
+	// the retries start over.
+	CreateRetryLimit    time.Duration
+	CreateRetryCooldown time.Duration
+
+	// NodeListTTL is how long a listing of the VMs of a host group is reused
+	// by other VMs of the host group. Concurrent listings of the same host
+	// group are always shared, unless DisableNodeListSharing is set, in
+	// which case every VM lists its host group itself.
+	NodeListTTL            time.Duration
+	DisableNodeListSharing bool
+
+	// StatsTTL is how long a listing of the stats of all VMs is reused by
+	// other VMs of the same Slicer API. It is not used if
+	// DisableNodeListSharing is set, in which case every VM reads its own
+	// stats.
+	StatsTTL time.Duration
+}
+
 // SetupGated adds a controller that reconciles VM managed resources with safe-start support.
-func SetupGated(mgr ctrl.Manager, o controller.Options) error {
+func SetupGated(mgr ctrl.Manager, o controller.Options, vo Options) error {
 	o.Gate.Register(func() {
-		if err := Setup(mgr, o); err != nil {
+		if err := Setup(mgr, o, vo); err != nil {
 			panic(errors.Wrap(err, "cannot setup VM controller"))
 		}
 	}, v1alpha1.VMGroupVersionKind)
@@ -58,17 +263,50 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 }
 
 // Setup adds a controller that reconciles VM managed resources.
-func Setup(mgr ctrl.Manager, o controller.Options) error {
+func Setup(mgr ctrl.Manager, o controller.Options, vo Options) error {
 	name := managed.ControllerName(v1alpha1.VMGroupKind)
 
+	if vo.MaxConcurrentReconciles > 0 {
+		o.MaxConcurrentReconciles = vo.MaxConcurrentReconciles
+	}
+
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
+	var nodes *nodeLister
+	var stats *statsLister
+	if !vo.DisableNodeListSharing {
+		nodes = newNodeLister(vo.NodeListTTL)
+		stats = newStatsLister(vo.StatsTTL)
+	}
+
 	opts := []managed.ReconcilerOption{
 		managed.WithExternalConnector(&connector{
-			kube:  mgr.GetClient(),
-			usage: resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			kube:     mgr.GetClient(),
+			reader:   mgr.GetAPIReader(),
+			usage:    resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
+			features: o.Features,
+			logger:   o.Logger.WithValues("controller", name),
+			metrics:  vo.StateMetrics,
+			calls:    vo.APIMetrics,
+			recorder: recorder,
+			nodes:    nodes,
+			stats:    stats,
+			throttle: newThrottles(),
+			creates:  newCreateSlots(),
+
+			createRetryLimit:    vo.CreateRetryLimit,
+			createRetryCooldown: vo.CreateRetryCooldown,
 		}),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithPollInterval(o.PollInterval),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollIntervalHook(pollIntervalHook(vo.MinPollInterval)),
+		managed.WithRecorder(recorder),
+		managed.WithCreationGracePeriod(vo.CreationGracePeriod),
+		managed.WithFinalizer(&clusterSecretFinalizer{
+			Finalizer: resource.NewAPIFinalizer(mgr.GetClient(), managed.FinalizerName),
+			kube:      mgr.GetClient(),
+			metrics:   vo.StateMetrics,
+		}),
 	}
 
 	if o.Features.Enabled(feature.EnableBetaManagementPolicies) {
@@ -94,18 +332,59 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 
 	r := managed.NewReconciler(mgr, resource.ManagedKind(v1alpha1.VMGroupVersionKind), opts...)
 
+	co := o.ForControllerRuntime()
+	if vo.BackoffBase > 0 || vo.BackoffMax > 0 {
+		co.RateLimiter = workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](
+			durationOrDefault(vo.BackoffBase, defaultBackoffBase), durationOrDefault(vo.BackoffMax, defaultBackoffMax))
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
-		WithOptions(o.ForControllerRuntime()).
+		WithOptions(co).
 		WithEventFilter(resource.DesiredStateChanged()).
 		For(&v1alpha1.VM{}).
 		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
 }
 
+// pollIntervalHook returns a hook that lets an Available VM request a poll
+// interval other than the controller's using the poll interval annotation.
+// VMs that are still starting are polled more often, backing off the longer
+// they take to start. Intervals are never shorter than minInterval, to protect
+// the API.
+func pollIntervalHook(minInterval time.Duration) managed.PollIntervalHook {
+	return func(mg resource.Managed, pollInterval time.Duration) time.Duration {
+		ready := mg.GetCondition(xpv1.TypeReady)
+		if ready.Reason == xpv1.ReasonCreating && meta.GetExternalName(mg) != "" {
+			return min(max(time.Since(ready.LastTransitionTime.Time)/2, minInterval), pollInterval)
+		}
+		if ready.Reason != xpv1.ReasonAvailable {
+			return pollInterval
+		}
+		d, err := time.ParseDuration(mg.GetAnnotations()[annotationPollInterval])
+		if err != nil || d <= 0 {
+			return pollInterval
+		}
+		return max(d, minInterval)
+	}
+}
+
 // connector produces an ExternalClient when its Connect method is called.
 type connector struct {
-	kube  client.Client
-	usage *resource.ProviderConfigUsageTracker
+	kube     client.Client
+	reader   client.Reader
+	usage    *resource.ProviderConfigUsageTracker
+	features *feature.Flags
+	logger   logging.Logger
+	metrics  *metrics.VMStates
+	calls    *metrics.APICalls
+	recorder event.Recorder
+	nodes    *nodeLister
+	stats    *statsLister
+	throttle *throttles
+	creates  *createSlots
+
+	createRetryLimit    time.Duration
+	createRetryCooldown time.Duration
 }
 
 // slicerConfig holds the configuration needed to create a Slicer client.
@@ -113,6 +392,35 @@ type slicerConfig struct {
 	URL       string
 	Token     string
 	HostGroup string
+	MaxCPUs   int
+	MaxRAMGB  int
+
+	// MaxConcurrentCreates limits the creates in flight per host group.
+	MaxConcurrentCreates int
+
+	HostGroupSelection *apisv1alpha1.HostGroupSelection
+
+	// DefaultCPUs and DefaultRAMGB apply to VMs that do not specify them.
+	DefaultCPUs  int
+	DefaultRAMGB int
+
+	// AllowedEndpointOverrides are the URLs a VM may use instead of URL.
+	AllowedEndpointOverrides []string
+
+	// TLSConfig verifies the API's certificate, if it is not verified the
+	// default way.
+	TLSConfig *tls.Config
+
+	ConnectionDetails apisv1alpha1.ConnectionDetailsOptions
+	MetadataTags      *apisv1alpha1.MetadataTagOptions
+	Rightsizing       *apisv1alpha1.RightsizingOptions
+	Timeouts          apisv1alpha1.OperationTimeouts
+	MaxRetries        int
+	RestartDetection  apisv1alpha1.RestartDetectionOptions
+	UpdatePolicy      apisv1alpha1.UpdatePolicy
+
+	// ClusterSecretNamespace is only set for ClusterProviderConfigs.
+	ClusterSecretNamespace string
 }
 
 // Connect produces an ExternalClient by getting credentials from the ProviderConfig.
@@ -128,6 +436,8 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 
 	var cfg slicerConfig
 	var cd apisv1alpha1.ProviderCredentials
+	var tlsOpts *apisv1alpha1.TLSOptions
+	var tlsNamespace string
 
 	// Get ProviderConfigRef
 	m := mg.(resource.ModernManaged)
@@ -141,7 +451,23 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		}
 		cd = pc.Spec.Credentials
 		cfg.URL = pc.Spec.URL
+		cfg.AllowedEndpointOverrides = pc.Spec.AllowedEndpointOverrides
+		tlsOpts = pc.Spec.TLS
+		tlsNamespace = pc.GetNamespace()
 		cfg.HostGroup = pc.Spec.HostGroup
+		cfg.HostGroupSelection = pc.Spec.HostGroupSelection
+		cfg.DefaultCPUs = pc.Spec.DefaultCPUs
+		cfg.DefaultRAMGB = pc.Spec.DefaultRAMGB
+		cfg.MaxCPUs = pc.Spec.MaxCPUs
+		cfg.MaxRAMGB = pc.Spec.MaxRAMGB
+		cfg.MaxConcurrentCreates = pc.Spec.MaxConcurrentCreates
+		cfg.ConnectionDetails = pc.Spec.ConnectionDetails
+		cfg.MetadataTags = pc.Spec.MetadataTags
+		cfg.Rightsizing = pc.Spec.Rightsizing
+		cfg.Timeouts = pc.Spec.Timeouts
+		cfg.MaxRetries = pc.Spec.MaxRetries
+		cfg.RestartDetection = pc.Spec.RestartDetection
+		cfg.UpdatePolicy = pc.Spec.UpdatePolicy
 	case "ClusterProviderConfig":
 		cpc := &apisv1alpha1.ClusterProviderConfig{}
 		if err := c.kube.Get(ctx, types.NamespacedName{Name: ref.Name}, cpc); err != nil {
@@ -149,7 +475,23 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		}
 		cd = cpc.Spec.Credentials
 		cfg.URL = cpc.Spec.URL
+		cfg.AllowedEndpointOverrides = cpc.Spec.AllowedEndpointOverrides
+		tlsOpts = cpc.Spec.TLS
 		cfg.HostGroup = cpc.Spec.HostGroup
+		cfg.HostGroupSelection = cpc.Spec.HostGroupSelection
+		cfg.DefaultCPUs = cpc.Spec.DefaultCPUs
+		cfg.DefaultRAMGB = cpc.Spec.DefaultRAMGB
+		cfg.MaxCPUs = cpc.Spec.MaxCPUs
+		cfg.MaxRAMGB = cpc.Spec.MaxRAMGB
+		cfg.MaxConcurrentCreates = cpc.Spec.MaxConcurrentCreates
+		cfg.ConnectionDetails = cpc.Spec.ConnectionDetails
+		cfg.MetadataTags = cpc.Spec.MetadataTags
+		cfg.Rightsizing = cpc.Spec.Rightsizing
+		cfg.Timeouts = cpc.Spec.Timeouts
+		cfg.MaxRetries = cpc.Spec.MaxRetries
+		cfg.RestartDetection = cpc.Spec.RestartDetection
+		cfg.UpdatePolicy = cpc.Spec.UpdatePolicy
+		cfg.ClusterSecretNamespace = cpc.Spec.ConnectionSecretNamespace
 	default:
 		return nil, errors.Errorf("unsupported provider config kind: %s", ref.Kind)
 	}
@@ -158,30 +500,186 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	if cfg.URL == "" {
 		cfg.URL = "http://127.0.0.1:8080"
 	}
+
+	// A VM may only select endpoints its provider config trusts with its
+	// credentials.
+	if o := cr.Spec.ForProvider.EndpointOverride; o != "" {
+		if !slices.Contains(cfg.AllowedEndpointOverrides, o) {
+			return nil, errors.Errorf(errEndpointNotAllowed, o)
+		}
+		cfg.URL = o
+	}
 	if cfg.HostGroup == "" {
 		cfg.HostGroup = "api"
 	}
+	if cfg.DefaultCPUs == 0 {
+		cfg.DefaultCPUs = defaultCPUs
+	}
+	if cfg.DefaultRAMGB == 0 {
+		cfg.DefaultRAMGB = defaultRAMGB
+	}
 
-	// Get credentials
+	// Get credentials. Secrets, environment variables and files are
+	// supported as sources.
 	data, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	if kerrors.IsNotFound(err) {
+		// The secret may simply not have been created yet. The reconcile is
+		// retried with backoff until it is.
+		cr.SetConditions(v1alpha1.CredentialsPending().WithMessage(err.Error()))
+		return nil, errors.Wrap(err, errCredsPending)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, errGetCreds)
 	}
-	cfg.Token = string(data)
+	if cr.GetCondition(v1alpha1.TypeCredentialsPending).Status != corev1.ConditionUnknown {
+		cr.SetConditions(v1alpha1.CredentialsResolved())
+	}
+	// Tokens read from files usually end with a newline.
+	cfg.Token = strings.TrimSpace(string(data))
+	if cfg.Token == "" {
+		return nil, errors.Errorf(errEmptyToken, cd.Source)
+	}
+
+	if tlsOpts != nil {
+		if cfg.TLSConfig, err = tlsConfigFor(ctx, c.kube, tlsOpts, tlsNamespace); err != nil {
+			return nil, err
+		}
+		if tlsOpts.InsecureSkipVerify {
+			c.logger.Info("WARNING: TLS certificate verification of the Slicer API is disabled by the provider config. The API token is exposed to anyone able to intercept connections.", "url", cfg.URL, "providerConfig", ref.Name)
+		}
+	}
 
 	// Create Slicer client
-	slicerClient := sdk.NewSlicerClient(cfg.URL, cfg.Token, "provider-slicervm/1.0", nil)
+	transport := transportFor(cfg)
+	hc, apiErrors := httpClientFor(cr, cfg, transport, c.throttle)
+	slicerClient := sdk.NewSlicerClient(cfg.URL, cfg.Token, userAgentFor(cr), hc)
 
 	return &external{
-		client:    slicerClient,
-		hostGroup: cfg.HostGroup,
+		kube:               c.kube,
+		reader:             c.reader,
+		logger:             c.logger.WithValues("vm", cr.GetNamespace()+"/"+cr.GetName()),
+		metrics:            c.metrics,
+		calls:              c.calls,
+		recorder:           c.recorder,
+		client:             slicerClient,
+		apiErrors:          apiErrors,
+		transport:          transport,
+		nodes:              c.nodes,
+		stats:              c.stats,
+		apiID:              apiID(cfg.URL, cfg.Token),
+		hostGroup:          cfg.HostGroup,
+		maxCPUs:            cfg.MaxCPUs,
+		maxRAMGB:           cfg.MaxRAMGB,
+		creates:            c.creates,
+		maxCreates:         cfg.MaxConcurrentCreates,
+		hostGroupSelection: cfg.HostGroupSelection,
+		defaultCPUs:        cfg.DefaultCPUs,
+		defaultRAMGB:       cfg.DefaultRAMGB,
+		connectionDetails:  cfg.ConnectionDetails,
+		metadataTags:       cfg.MetadataTags,
+		rightsizing:        cfg.Rightsizing,
+		createTimeout:      durationOr(cfg.Timeouts.Create, defaultCreateTimeout),
+		deleteTimeout:      durationOr(cfg.Timeouts.Delete, defaultDeleteTimeout),
+		observeTimeout:     durationOr(cfg.Timeouts.Observe, defaultObserveTimeout),
+		updateTimeout:      durationOr(cfg.Timeouts.Update, defaultUpdateTimeout),
+		shutdownTimeout:    durationOr(cfg.Timeouts.Shutdown, defaultShutdownTimeout),
+		cloudInitTimeout:   durationOr(cfg.Timeouts.CloudInit, defaultCloudInitTimeout),
+		restartThreshold:   cfg.RestartDetection.Threshold,
+		restartWindow:      durationOr(cfg.RestartDetection.Window, defaultRestartWindow),
+		updatePolicy:       cfg.UpdatePolicy,
+		requireGuestAgent:  c.features.Enabled(features.EnableAlphaGuestAgentReadiness),
+		probeReachability:  c.features.Enabled(features.EnableAlphaReachabilityProbe),
+
+		clusterSecretNamespace: cfg.ClusterSecretNamespace,
+		createRetryLimit:       c.createRetryLimit,
+		createRetryCooldown:    c.createRetryCooldown,
 	}, nil
 }
 
+// nodesKey returns the key under which the VMs of the supplied host group are
+// listed.
+func (e *external) nodesKey(hostGroup string) string {
+	return e.apiID + "/" + hostGroup
+}
+
+// durationOrDefault returns the supplied duration, or the default if it is
+// not positive.
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// durationOr returns the supplied duration, or the default if it is unset.
+func durationOr(d *metav1.Duration, def time.Duration) time.Duration {
+	if d == nil {
+		return def
+	}
+	return durationOrDefault(d.Duration, def)
+}
+
+// userAgentFor returns the user-agent to use for the supplied VM's API calls.
+func userAgentFor(cr *v1alpha1.VM) string {
+	id := strings.TrimSpace(cr.GetAnnotations()[annotationCorrelationID])
+	if id == "" {
+		return userAgent
+	}
+	return userAgent + " (correlation-id " + id + ")"
+}
+
+// slicerAPI is the part of the Slicer API used to manage VMs. It is satisfied
+// by the SDK's client, and lets external be used with other implementations.
+type slicerAPI interface {
+	GetHostGroupNodes(ctx context.Context, groupName string) ([]sdk.SlicerNode, error)
+	CreateNode(ctx context.Context, groupName string, request sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error)
+	DeleteVM(ctx context.Context, groupName, hostname string) (*sdk.SlicerDeleteResponse, error)
+	GetVMStats(ctx context.Context, hostname string) ([]sdk.SlicerNodeStat, error)
+	Exec(ctx context.Context, nodeName string, execReq sdk.SlicerExecRequest) (chan sdk.SlicerExecWriteResult, error)
+}
+
+var _ slicerAPI = &sdk.SlicerClient{}
+
 // external observes, creates, updates, or deletes VMs using the Slicer SDK.
 type external struct {
-	client    *sdk.SlicerClient
-	hostGroup string
+	kube               client.Client
+	reader             client.Reader
+	logger             logging.Logger
+	metrics            *metrics.VMStates
+	calls              *metrics.APICalls
+	recorder           event.Recorder
+	client             slicerAPI
+	apiErrors          *errorRecorder
+	transport          *http.Transport
+	nodes              *nodeLister
+	stats              *statsLister
+	apiID              string
+	hostGroup          string
+	maxCPUs            int
+	maxRAMGB           int
+	creates            *createSlots
+	maxCreates         int
+	hostGroupSelection *apisv1alpha1.HostGroupSelection
+	defaultCPUs        int
+	defaultRAMGB       int
+	connectionDetails  apisv1alpha1.ConnectionDetailsOptions
+	metadataTags       *apisv1alpha1.MetadataTagOptions
+	rightsizing        *apisv1alpha1.RightsizingOptions
+	createTimeout      time.Duration
+	deleteTimeout      time.Duration
+	observeTimeout     time.Duration
+	updateTimeout      time.Duration
+	shutdownTimeout    time.Duration
+	cloudInitTimeout   time.Duration
+	restartThreshold   int
+	restartWindow      time.Duration
+	updatePolicy       apisv1alpha1.UpdatePolicy
+	requireGuestAgent  bool
+	probeReachability  bool
+
+	clusterSecretNamespace string
+	createRetryLimit       time.Duration
+	createRetryCooldown    time.Duration
 }
 
 func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -190,21 +688,73 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.New(errNotVM)
 	}
 
+	obs, err := e.observe(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	// The cluster connection secret cannot be owned by the VM, so it is
+	// deleted explicitly once the VM is being deleted. Delete is not called
+	// for VMs that are already gone or foreign, so it is deleted here rather
+	// than there. VMs orphaned by their management policies are not observed
+	// either; their secret is deleted by the clusterSecretFinalizer.
+	if meta.WasDeleted(cr) {
+		if err := e.unpublishClusterSecret(ctx, cr); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+	}
+	return obs, nil
+}
+
+// observe observes the supplied VM.
+func (e *external) observe(ctx context.Context, cr *v1alpha1.VM) (managed.ExternalObservation, error) {
 	// Get external name (hostname)
 	externalName := meta.GetExternalName(cr)
 	if externalName == "" {
-		return managed.ExternalObservation{ResourceExists: false}, nil
+		if cr.GetAnnotations()[annotationDryRun] == "true" && !meta.WasDeleted(cr) {
+			return e.dryRun(ctx, cr), nil
+		}
+		if cr.GetCondition(v1alpha1.TypeDryRun).Status != corev1.ConditionUnknown {
+			cr.SetConditions(v1alpha1.DryRunDisabled())
+		}
+		return e.notFound(cr), nil
 	}
 
-	// Get host group
-	hostGroup := cr.Spec.ForProvider.HostGroup
-	if hostGroup == "" {
-		hostGroup = e.hostGroup
+	// A VM cannot move between host groups. Refuse the change rather than
+	// recreating the VM in the new group and leaking the old one, but still
+	// allow a VM whose host group was changed to be deleted.
+	hostGroup := e.hostGroupFor(cr)
+	if e.selectingHostGroup(cr) {
+		// The host group selected for the VM was not recorded, for example
+		// because the status update of its create was lost, so look for it
+		// in every candidate.
+		_, hg, err := e.findNode(ctx, cr, hostGroup, func(n *sdk.SlicerNode) bool { return n.Hostname == externalName })
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		if hg == "" {
+			e.logger.Debug("VM not found in any candidate host group", "hostname", externalName)
+			return e.notFound(cr), nil
+		}
+		hostGroup = hg
+	}
+	if prev := cr.Status.AtProvider.HostGroup; prev != "" && prev != hostGroup {
+		if !meta.WasDeleted(cr) {
+			return managed.ExternalObservation{}, errors.Errorf(errHostGroupChanged, prev, hostGroup)
+		}
+		hostGroup = prev
 	}
 
-	// List VMs in the host group and find our VM
-	nodes, err := e.client.GetHostGroupNodes(ctx, hostGroup)
-	if err != nil {
+	// List VMs in the host group and find our VM. VMs of the same host group
+	// that are observed together share the listing.
+	nodes, err := e.listNodes(ctx, hostGroup)
+	switch {
+	case isNotFound(err) && meta.WasDeleted(cr):
+		// The VM was deleted along with its host group.
+		return e.notFound(cr), nil
+	case isNotFound(err):
+		return managed.ExternalObservation{}, errors.Errorf(errHostGroupNotFound, hostGroup)
+	case err != nil:
 		return managed.ExternalObservation{}, errors.Wrap(err, "cannot list VMs")
 	}
 
@@ -217,121 +767,1539 @@ func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	if found == nil {
-		return managed.ExternalObservation{ResourceExists: false}, nil
+		e.logger.Debug("VM not found", "hostname", externalName, "host-group", hostGroup)
+		return e.notFound(cr), nil
+	}
+
+	// Never claim a VM that is managed by something else. A foreign VM is
+	// released rather than deleted when its resource is deleted.
+	cr.Status.AtProvider.ManagedBy = managedBy(cr, found)
+	if foreign(cr, found) {
+		if meta.WasDeleted(cr) {
+			return e.notFound(cr), nil
+		}
+		cr.SetConditions(v1alpha1.ForeignResource())
+		return managed.ExternalObservation{}, errors.Errorf(errForeignVM, found.Hostname)
+	}
+	if cr.GetCondition(v1alpha1.TypeForeignResource).Status != corev1.ConditionUnknown {
+		cr.SetConditions(v1alpha1.OwnedResource())
 	}
 
 	// Update observed state
 	cr.Status.AtProvider.Hostname = found.Hostname
+	cr.Status.AtProvider.HostGroup = hostGroup
 	cr.Status.AtProvider.IP = found.IP
+	cr.Status.AtProvider.Arch = found.Arch
+	cr.Status.AtProvider.Tags = found.Tags
+	cr.Status.AtProvider.Metadata = metadataOf(found.Tags)
 	cr.Status.AtProvider.CreatedAt = found.CreatedAt.String()
-	cr.Status.AtProvider.State = "running"
+	cr.Status.AtProvider.State = stateOf(found)
+	e.logger.Debug("Observed VM", "hostname", found.Hostname, "host-group", hostGroup, "ip", found.IP, "state", cr.Status.AtProvider.State)
+	stats := e.observedStats(ctx, hostGroup, found.Hostname)
+	cr.Status.AtProvider.GuestAgentReady = guestAgentReady(stats)
+	cr.Status.AtProvider.PublicIP = isPublicIP(found.IP)
+	cr.Status.AtProvider.SSHExposed = cr.Status.AtProvider.PublicIP && sshConfigured(cr)
+	if e.probeReachability {
+		e.probe(ctx, cr, found.IP)
+	}
+	e.checkHealth(ctx, cr, found.IP)
+
+	setMaintenance(cr)
+	setHealth(cr)
+	e.setResizeRecommendation(cr, stats)
+	e.observeRestarts(cr, stats)
+	observeDisk(cr, stats)
+	observeSize(cr, stats)
+	lateInitialized := e.lateInitialize(cr, found)
+
+	if expired(cr, found.CreatedAt) {
+		cr.SetConditions(v1alpha1.Expired())
+		if !meta.WasDeleted(cr) && deletable(cr) {
+			if err := e.deleteExpired(ctx, cr); err != nil {
+				return managed.ExternalObservation{}, err
+			}
+		}
+	}
+
+	var cloudInit *xpv1.Condition
+	if cr.Spec.ForProvider.WaitForCloudInit && cr.Status.AtProvider.State == stateRunning {
+		cloudInit = e.cloudInitReadiness(ctx, cr, found)
+	}
+
+	wasAvailable := cr.GetCondition(xpv1.TypeReady).Reason == xpv1.ReasonAvailable
+	switch state := cr.Status.AtProvider.State; {
+	case state != stateRunning:
+		cr.SetConditions(xpv1.Creating().WithMessage(fmt.Sprintf("VM is %s", state)))
+	case e.requireGuestAgent && !cr.Status.AtProvider.GuestAgentReady:
+		cr.SetConditions(xpv1.Unavailable().WithMessage("guest agent has not reported in"))
+	case e.probeReachability && !ptr.Deref(cr.Status.AtProvider.Reachable, false):
+		cr.SetConditions(xpv1.Unavailable().WithMessage("VM is not reachable"))
+	case healthCheckFailing(cr) && cr.Spec.ForProvider.HealthCheck.AffectsReadiness:
+		cr.SetConditions(xpv1.Unavailable().WithMessage("health check failed: " + cr.Status.AtProvider.HealthCheckError))
+	case cloudInit != nil:
+		cr.SetConditions(*cloudInit)
+	default:
+		cr.SetConditions(xpv1.Available())
+	}
+	if !wasAvailable && cr.GetCondition(xpv1.TypeReady).Reason == xpv1.ReasonAvailable {
+		e.recorder.Event(cr, event.Normal(reasonAvailable, fmt.Sprintf("VM %s is available at %s", found.Hostname, found.IP)))
+	}
+
+	// VMs created before create parameters were recorded are assumed to
+	// have been created with their current parameters.
+	if cr.Status.AtProvider.CreateParametersHash == "" {
+		cr.Status.AtProvider.CreateParametersHash = e.createParametersHash(cr)
+	}
+	recreate := e.recreateRequired(cr)
+	resized := e.sizeDrift(cr)
+	switch {
+	case recreate:
+		cr.SetConditions(v1alpha1.RecreateRequired().WithMessage(errRecreateRequired))
+	case resized != "":
+		cr.SetConditions(v1alpha1.RecreateRequired().WithMessage(resized))
+	case cr.GetCondition(v1alpha1.TypeRecreateRequired).Status != corev1.ConditionUnknown:
+		cr.SetConditions(v1alpha1.ParametersApplied())
+	}
+
+	drift, err := e.tagDrift(ctx, cr, found.Tags)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	// Slicer cannot update the tags of a VM, and recreating a VM only to
+	// change its tags is too disruptive. Drifted tags are therefore only
+	// reported, and do not keep the VM from being synced.
+	switch {
+	case drift != "":
+		if cr.GetCondition(v1alpha1.TypeTagsDrifted).Status != corev1.ConditionTrue {
+			e.recorder.Event(cr, event.Warning(reasonTagsDrifted, errors.Errorf(errTagsDrifted, drift)))
+		}
+		cr.SetConditions(v1alpha1.TagsDrifted().WithMessage(drift))
+	case cr.GetCondition(v1alpha1.TypeTagsDrifted).Status != corev1.ConditionUnknown:
+		cr.SetConditions(v1alpha1.TagsInSync())
+	}
+
+	upToDate := !rebootRequested(cr) && !recreate && resized == ""
+	e.recordState(cr, upToDate)
 
-	cr.SetConditions(xpv1.Available())
+	cd := e.connectionDetailsFor(found.Hostname, found.IP, found.Tags)
+	if !meta.WasDeleted(cr) {
+		if err := e.publishClusterSecret(ctx, cr, cd); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+	}
 
 	return managed.ExternalObservation{
-		ResourceExists:    true,
-		ResourceUpToDate:  true,
-		ConnectionDetails: managed.ConnectionDetails{},
+		ResourceExists:          true,
+		ResourceUpToDate:        upToDate,
+		ResourceLateInitialized: lateInitialized,
+		ConnectionDetails:       cd,
 	}, nil
 }
 
-func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
-	cr, ok := mg.(*v1alpha1.VM)
-	if !ok {
-		return managed.ExternalCreation{}, errors.New(errNotVM)
+// connectionDetailsFor returns the connection details of a VM, including the
+// optional details enabled by the ProviderConfig.
+func (e *external) connectionDetailsFor(hostname, ip string, tags []string) managed.ConnectionDetails {
+	cd := managed.ConnectionDetails{
+		"hostname": []byte(hostname),
+		"username": []byte(e.sshUser()),
+		"port":     []byte(strconv.Itoa(e.sshPort())),
+	}
+	// A VM that is still starting has no IP yet.
+	if ip != "" {
+		cd["ip"] = []byte(ip)
+		cd["endpoint"] = []byte(fmt.Sprintf("%s@%s", e.sshUser(), hostPort(ip, e.sshPort())))
+	}
+	if e.connectionDetails.IncludeTags {
+		published := []string{}
+		for _, t := range tags {
+			if !strings.HasPrefix(t, tagOwnerPrefix) {
+				published = append(published, t)
+			}
+		}
+		// Marshalling a string slice cannot fail.
+		cd["tags"], _ = json.Marshal(published)
+	}
+	if e.connectionDetails.SSHConfig {
+		cd["ssh_config"] = []byte(e.sshConfigFor(hostname, ip))
 	}
+	return cd
+}
 
-	cr.SetConditions(xpv1.Creating())
+// sshConfigFor renders an SSH config block for the supplied VM.
+func (e *external) sshConfigFor(hostname, ip string) string {
+	if addr, ok := parseIP(ip); ok {
+		ip = addr.String()
+	}
+	return fmt.Sprintf("Host %s\n  HostName %s\n  User %s\n  Port %d\n", hostname, ip, e.sshUser(), e.sshPort())
+}
 
-	// Get host group
-	hostGroup := cr.Spec.ForProvider.HostGroup
-	if hostGroup == "" {
-		hostGroup = e.hostGroup
+// sshUser returns the user to connect to VMs with over SSH.
+func (e *external) sshUser() string {
+	if e.connectionDetails.SSHUser == "" {
+		return "ubuntu"
+	}
+	return e.connectionDetails.SSHUser
+}
+
+// sshPort returns the port VMs accept SSH connections on.
+func (e *external) sshPort() int {
+	if e.connectionDetails.SSHPort == 0 {
+		return 22
 	}
+	return e.connectionDetails.SSHPort
+}
 
-	// Build request
-	req := sdk.SlicerCreateNodeRequest{
-		RamGB:    cr.Spec.ForProvider.RAMGB,
-		CPUs:     cr.Spec.ForProvider.CPUs,
-		Userdata: cr.Spec.ForProvider.Userdata,
+// hostPort joins the supplied IP, which may be in CIDR notation, and port.
+func hostPort(ip string, port int) string {
+	if addr, ok := parseIP(ip); ok {
+		ip = addr.String()
 	}
+	return net.JoinHostPort(ip, strconv.Itoa(port))
+}
 
-	if req.RamGB == 0 {
-		req.RamGB = 4
+// setHealth sets a Healthy condition summarizing the observed health of the
+// supplied VM. A VM is healthy when it is running, its guest agent is
+// reporting in, it is reachable if reachability probing is enabled, and its
+// health check passes if it has one. The Slicer API does not report host
+// health, so VMs are never considered unhealthy because of their host.
+func setHealth(cr *v1alpha1.VM) {
+	var problems []string
+	if cr.Status.AtProvider.State != stateRunning {
+		problems = append(problems, "VM is not running")
+	}
+	if !cr.Status.AtProvider.GuestAgentReady {
+		problems = append(problems, "guest agent has not reported in")
+	}
+	if r := cr.Status.AtProvider.Reachable; r != nil && !*r {
+		problems = append(problems, "VM is not reachable")
+	}
+	if healthCheckFailing(cr) {
+		problems = append(problems, "health check failed: "+cr.Status.AtProvider.HealthCheckError)
 	}
-	if req.CPUs == 0 {
-		req.CPUs = 2
+	if len(problems) > 0 {
+		cr.SetConditions(v1alpha1.Unhealthy().WithMessage(strings.Join(problems, "; ")))
+		return
 	}
+	cr.SetConditions(v1alpha1.Healthy())
+}
+
+// listNodes lists the nodes of the supplied host group, sharing the listing
+// with VMs of the same host group that are observed together.
+func (e *external) listNodes(ctx context.Context, hostGroup string) ([]sdk.SlicerNode, error) {
+	return e.nodes.List(ctx, e.nodesKey(hostGroup), func(ctx context.Context) ([]sdk.SlicerNode, error) {
+		listCtx, cancel := context.WithTimeout(ctx, e.observeTimeout)
+		defer cancel()
+		e.logger.Debug("Listing VMs", "host-group", hostGroup, "operation", metrics.OperationListNodes)
+		start := time.Now()
+		nodes, err := e.client.GetHostGroupNodes(listCtx, hostGroup)
+		e.calls.Observe(metrics.OperationListNodes, hostGroup, start, err)
+		return nodes, e.apiErrors.wrap(err)
+	})
+}
 
-	if len(cr.Spec.ForProvider.SSHKeys) > 0 {
-		req.SSHKeys = cr.Spec.ForProvider.SSHKeys
+// dryRun returns the observation of a VM in dry-run mode, which is never
+// created. Its parameters are resolved as they would be for a create, and its
+// host group is looked up, setting a DryRun condition with the result. The VM
+// is reported as existing but unavailable, so it is neither created nor
+// becomes ready.
+func (e *external) dryRun(ctx context.Context, cr *v1alpha1.VM) managed.ExternalObservation {
+	_, err := e.createRequest(ctx, cr)
+	if err == nil {
+		var hostGroup string
+		if hostGroup, err = e.createHostGroupFor(ctx, cr); err == nil {
+			if _, err = e.listNodes(ctx, hostGroup); isNotFound(err) {
+				err = errors.Errorf(errHostGroupNotFound, hostGroup)
+			}
+		}
 	}
+	if err != nil {
+		cr.SetConditions(v1alpha1.DryRunRejected().WithMessage(err.Error()))
+	} else {
+		cr.SetConditions(v1alpha1.DryRunAccepted())
+	}
+	cr.SetConditions(xpv1.Unavailable().WithMessage(msgDryRun))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+}
 
-	if cr.Spec.ForProvider.ImportUser != "" {
-		req.ImportUser = cr.Spec.ForProvider.ImportUser
+// notFound returns the observation of a VM that does not exist, which is no
+// longer counted by the state metrics.
+func (e *external) notFound(cr *v1alpha1.VM) managed.ExternalObservation {
+	e.metrics.Delete(types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()})
+	return managed.ExternalObservation{ResourceExists: false}
+}
+
+// recordState records the observed state of an existing VM in the state
+// metrics.
+func (e *external) recordState(cr *v1alpha1.VM, upToDate bool) {
+	state := metrics.StateUnavailable
+	switch {
+	case !upToDate:
+		state = metrics.StateDrifted
+	case cr.GetCondition(xpv1.TypeReady).Reason == xpv1.ReasonAvailable:
+		state = metrics.StateAvailable
+	case cr.GetCondition(xpv1.TypeReady).Reason == xpv1.ReasonCreating:
+		state = metrics.StateCreating
 	}
+	e.metrics.Set(types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}, state)
+}
 
-	if len(cr.Spec.ForProvider.Tags) > 0 {
-		req.Tags = cr.Spec.ForProvider.Tags
+// hostGroupFor returns the host group the supplied VM should be in. A VM whose
+// host group was selected from candidates stays in the selected host group.
+func (e *external) hostGroupFor(cr *v1alpha1.VM) string {
+	if cr.Spec.ForProvider.HostGroup != "" {
+		return cr.Spec.ForProvider.HostGroup
 	}
+	if e.hostGroupSelection != nil && cr.Status.AtProvider.HostGroup != "" {
+		return cr.Status.AtProvider.HostGroup
+	}
+	return e.hostGroup
+}
 
-	// Create VM
-	resp, err := e.client.CreateNode(ctx, hostGroup, req)
-	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, "cannot create VM")
+// selectingHostGroup returns true if the host group of the supplied VM is
+// selected from candidates, but none was recorded for it yet.
+func (e *external) selectingHostGroup(cr *v1alpha1.VM) bool {
+	return e.hostGroupSelection != nil && cr.Spec.ForProvider.HostGroup == "" && cr.Status.AtProvider.HostGroup == ""
+}
+
+// createHostGroupFor returns the host group to create the supplied VM in,
+// selecting one from the candidates if the VM is not in one already.
+func (e *external) createHostGroupFor(ctx context.Context, cr *v1alpha1.VM) (string, error) {
+	if !e.selectingHostGroup(cr) {
+		return e.hostGroupFor(cr), nil
 	}
+	s := e.hostGroupSelection
 
-	// Set external name to hostname
-	meta.SetExternalName(cr, resp.Hostname)
+	// LeastLoaded is the only strategy.
+	selected, fewest := "", 0
+	for _, hg := range s.HostGroups {
+		nodes, err := e.listNodes(ctx, hg)
+		if isNotFound(err) {
+			return "", errors.Errorf(errHostGroupNotFound, hg)
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, errListCandidate, hg)
+		}
+		if s.MaxVMs > 0 && len(nodes) >= s.MaxVMs {
+			continue
+		}
+		if selected == "" || len(nodes) < fewest {
+			selected, fewest = hg, len(nodes)
+		}
+	}
+	if selected == "" {
+		return "", errors.Errorf(errHostGroupsFull, strings.Join(s.HostGroups, ", "), s.MaxVMs)
+	}
+	return selected, nil
+}
 
-	// Update status
-	cr.Status.AtProvider.Hostname = resp.Hostname
-	cr.Status.AtProvider.IP = resp.IP
-	cr.Status.AtProvider.CreatedAt = resp.CreatedAt.String()
+// ownerTag returns the tag that marks a Slicer VM as owned by the supplied VM
+// resource.
+func ownerTag(cr *v1alpha1.VM) string {
+	return tagOwnerPrefix + string(cr.GetUID())
+}
 
-	return managed.ExternalCreation{
-		ConnectionDetails: managed.ConnectionDetails{
-			"hostname": []byte(resp.Hostname),
-			"ip":       []byte(resp.IP),
-		},
-	}, nil
+// foreign returns true if the supplied Slicer VM is not owned by the supplied
+// VM resource. Untagged VMs, such as those created before owner tagging was
+// introduced, are only recognised if the resource has observed them before or
+// is annotated to adopt them.
+func foreign(cr *v1alpha1.VM, n *sdk.SlicerNode) bool {
+	for _, t := range n.Tags {
+		if strings.HasPrefix(t, tagOwnerPrefix) {
+			return t != ownerTag(cr)
+		}
+	}
+	return cr.Status.AtProvider.Hostname != n.Hostname && cr.GetAnnotations()[annotationAdopt] != "true"
 }
 
-func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
-	// Slicer VMs cannot be updated in place, only recreated
-	// Return without error - Observe will handle the state
-	return managed.ExternalUpdate{}, nil
+// lateInitialize fills the unset size and tags of the supplied VM from the
+// supplied Slicer VM and its observed size, so that an adopted VM's spec
+// reflects the VM. A VM the provider created was created with the provider
+// config's default size, so that is filled in instead. It returns true if the
+// spec was changed.
+func (e *external) lateInitialize(cr *v1alpha1.VM, n *sdk.SlicerNode) bool {
+	p, o := &cr.Spec.ForProvider, cr.Status.AtProvider
+	created := o.ManagedBy == v1alpha1.ManagedByProvider
+	changed := false
+	switch {
+	case p.CPUs != 0:
+	case created:
+		p.CPUs = e.defaultCPUs
+		changed = true
+	case o.CPUs > 0:
+		p.CPUs = o.CPUs
+		changed = true
+	}
+	switch {
+	case p.RAMGB != 0:
+	case created:
+		p.RAMGB = e.defaultRAMGB
+		changed = true
+	case o.MemoryBytes != nil:
+		p.RAMGB = ramGBOf(*o.MemoryBytes)
+		changed = true
+	}
+	if len(p.Tags) == 0 {
+		for _, t := range n.Tags {
+			if !strings.HasPrefix(t, tagOwnerPrefix) {
+				p.Tags = append(p.Tags, t)
+				changed = true
+			}
+		}
+	}
+	return changed
 }
 
-func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
-	cr, ok := mg.(*v1alpha1.VM)
-	if !ok {
-		return managed.ExternalDelete{}, errors.New(errNotVM)
+// managedBy returns who created the supplied Slicer VM, based on its owner
+// tag.
+func managedBy(cr *v1alpha1.VM, n *sdk.SlicerNode) v1alpha1.ManagedBy {
+	for _, t := range n.Tags {
+		switch {
+		case t == ownerTag(cr):
+			return v1alpha1.ManagedByProvider
+		case strings.HasPrefix(t, tagOwnerPrefix):
+			return v1alpha1.ManagedByOtherResource
+		}
+	}
+	return v1alpha1.ManagedByExternal
+}
+
+// expired returns true if the supplied VM, created at the supplied time, has
+// outlived its TTL.
+func expired(cr *v1alpha1.VM, createdAt time.Time) bool {
+	ttl := cr.Spec.ForProvider.TTLSeconds
+	if ttl == nil || createdAt.IsZero() {
+		return false
 	}
+	return time.Since(createdAt) >= time.Duration(*ttl)*time.Second
+}
 
-	cr.SetConditions(xpv1.Deleting())
+// deletable returns true if the management policies of the supplied VM allow
+// deleting it. Expired VMs that may not be deleted are only marked expired.
+func deletable(cr *v1alpha1.VM) bool {
+	p := cr.GetManagementPolicies()
+	return len(p) == 0 || slices.Contains(p, xpv1.ManagementActionAll) || slices.Contains(p, xpv1.ManagementActionDelete)
+}
 
-	// Get external name (hostname)
-	externalName := meta.GetExternalName(cr)
-	if externalName == "" {
-		return managed.ExternalDelete{}, nil
+// deleteExpired deletes the supplied expired VM resource, which deletes the VM
+// as any other deletion does. Deleting the resource changes its metadata, so
+// the change is read back from the API server, bypassing the cache, to keep
+// the status update that follows this observation from conflicting.
+func (e *external) deleteExpired(ctx context.Context, cr *v1alpha1.VM) error {
+	if err := e.kube.Delete(ctx, cr); resource.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, errDeleteExpired)
+	}
+	latest := &v1alpha1.VM{}
+	if err := e.reader.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}, latest); err != nil {
+		return errors.Wrap(resource.IgnoreNotFound(err), errDeleteExpired)
+	}
+	cr.SetResourceVersion(latest.GetResourceVersion())
+	cr.SetDeletionTimestamp(latest.GetDeletionTimestamp())
+	return nil
+}
+
+// stateOf returns the state of the supplied Slicer VM. The Slicer API reports
+// no state, and only lists VMs that have been started, so the state is derived:
+// a VM is still starting until it has been assigned an IP.
+func stateOf(n *sdk.SlicerNode) string {
+	if n.IP == "" {
+		return stateStarting
 	}
+	return stateRunning
+}
 
-	// Get host group
-	hostGroup := cr.Spec.ForProvider.HostGroup
-	if hostGroup == "" {
-		hostGroup = e.hostGroup
+// setMaintenance reflects the VM's maintenance mode in its conditions. The
+// Maintenance condition is only added once maintenance mode has been enabled.
+func setMaintenance(cr *v1alpha1.VM) {
+	switch {
+	case cr.Spec.ForProvider.MaintenanceMode:
+		cr.SetConditions(v1alpha1.MaintenanceEnabled())
+	case cr.GetCondition(v1alpha1.TypeMaintenance).Status != corev1.ConditionUnknown:
+		cr.SetConditions(v1alpha1.MaintenanceDisabled())
 	}
+}
 
-	// Delete VM
-	_, err := e.client.DeleteVM(ctx, hostGroup, externalName)
+// statsFor returns the stats of the supplied VM, or nil if the Slicer API
+// cannot report them.
+func (e *external) statsFor(ctx context.Context, hostGroup, hostname string) *sdk.SlicerNodeStat {
+	ctx, cancel := context.WithTimeout(ctx, e.observeTimeout)
+	defer cancel()
+	start := time.Now()
+	stats, err := e.client.GetVMStats(ctx, hostname)
+	e.calls.Observe(metrics.OperationGetVMStats, hostGroup, start, err)
 	if err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, "cannot delete VM")
+		return nil
 	}
+	return statOf(stats, hostname)
+}
 
-	return managed.ExternalDelete{}, nil
+// observedStats returns the stats of the supplied VM for an observation, or
+// nil if the Slicer API cannot report them. The stats of all VMs are listed
+// once and shared between the VMs observed shortly after, so observing a VM
+// does not cost an API call of its own. A VM missing from a shared listing,
+// for example because it was created after it, reads its own stats.
+func (e *external) observedStats(ctx context.Context, hostGroup, hostname string) *sdk.SlicerNodeStat {
+	if e.stats == nil {
+		return e.statsFor(ctx, hostGroup, hostname)
+	}
+	stats, err := e.stats.List(ctx, e.apiID, func(ctx context.Context) ([]sdk.SlicerNodeStat, error) {
+		listCtx, cancel := context.WithTimeout(ctx, e.observeTimeout)
+		defer cancel()
+		start := time.Now()
+		stats, err := e.client.GetVMStats(listCtx, "")
+		e.calls.Observe(metrics.OperationGetVMStats, "", start, err)
+		return stats, err
+	})
+	if err != nil {
+		return nil
+	}
+	if st := statOf(stats, hostname); st != nil {
+		return st
+	}
+	return e.statsFor(ctx, hostGroup, hostname)
 }
 
-func (e *external) Disconnect(ctx context.Context) error {
+// statOf returns the stats of the supplied VM from the supplied stats, or nil
+// if they do not include it.
+func statOf(stats []sdk.SlicerNodeStat, hostname string) *sdk.SlicerNodeStat {
+	for i := range stats {
+		if stats[i].Hostname == hostname {
+			return &stats[i]
+		}
+	}
+	return nil
+}
+
+// guestAgentReady returns true if the guest agent of a VM with the supplied
+// stats is reporting in. Agent status is treated as ready when the Slicer API
+// cannot report it, so that readiness never regresses on older Slicer versions.
+func guestAgentReady(st *sdk.SlicerNodeStat) bool {
+	return st == nil || (st.Error == "" && st.Snapshot != nil)
+}
+
+// setResizeRecommendation recommends resizing the supplied VM if rightsizing
+// is enabled and its stats show it to be over- or undersized. CPU utilization
+// is the 15 minute load average per CPU, so it reflects sustained usage.
+func (e *external) setResizeRecommendation(cr *v1alpha1.VM, st *sdk.SlicerNodeStat) {
+	if e.rightsizing == nil || st == nil || st.Snapshot == nil || st.Snapshot.TotalCPUS == 0 {
+		return
+	}
+	cpu := st.Snapshot.LoadAvg15 / float64(st.Snapshot.TotalCPUS) * 100
+	mem := st.Snapshot.MemoryUsedPercent
+	usage := fmt.Sprintf("CPU utilization is %.0f%%, memory utilization is %.0f%%", cpu, mem)
+
+	low, high := float64(e.rightsizing.LowUtilizationPercent), float64(e.rightsizing.HighUtilizationPercent)
+	switch {
+	case cpu > high || mem > high:
+		cr.SetConditions(v1alpha1.ResizeRecommended(v1alpha1.ReasonUndersized).WithMessage(usage + "; consider a larger size"))
+	case cpu < low && mem < low:
+		cr.SetConditions(v1alpha1.ResizeRecommended(v1alpha1.ReasonOversized).WithMessage(usage + "; consider a smaller size"))
+	default:
+		cr.SetConditions(v1alpha1.RightSized().WithMessage(usage))
+	}
+}
+
+// observeRestarts counts restarts of the supplied VM, detected as a change of
+// the boot time derived from its reported uptime, and sets a FrequentRestarts
+// condition if it restarts more often than the configured threshold.
+func (e *external) observeRestarts(cr *v1alpha1.VM, st *sdk.SlicerNodeStat) {
+	if st == nil || st.Snapshot == nil {
+		return
+	}
+	uptime, err := time.ParseDuration(st.Snapshot.Uptime)
+	if err != nil {
+		return
+	}
+	at := st.Snapshot.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	boot := metav1.NewTime(at.Add(-uptime).Truncate(time.Second))
+
+	o := &cr.Status.AtProvider
+	switch {
+	case o.BootTime == nil:
+		o.BootTime = &boot
+	case boot.After(o.BootTime.Add(bootTimeTolerance)):
+		o.BootTime = &boot
+		o.RestartCount++
+		o.RecentRestarts = append(o.RecentRestarts, boot)
+	}
+
+	recent := o.RecentRestarts[:0]
+	for _, t := range o.RecentRestarts {
+		if time.Since(t.Time) <= e.restartWindow {
+			recent = append(recent, t)
+		}
+	}
+	o.RecentRestarts = recent
+
+	threshold := e.restartThreshold
+	if threshold == 0 {
+		threshold = defaultRestartThreshold
+	}
+	switch {
+	case len(recent) > threshold:
+		cr.SetConditions(v1alpha1.FrequentRestarts().WithMessage(fmt.Sprintf("VM restarted %d times in the last %s", len(recent), e.restartWindow)))
+	case cr.GetCondition(v1alpha1.TypeFrequentRestarts).Status != corev1.ConditionUnknown:
+		cr.SetConditions(v1alpha1.Stable())
+	}
+}
+
+// observeDisk records the disk size and I/O of the supplied VM from its stats.
+// The fields are cleared when the stats do not report them.
+func observeDisk(cr *v1alpha1.VM, st *sdk.SlicerNodeStat) {
+	o := &cr.Status.AtProvider
+	if st == nil || st.Snapshot == nil {
+		o.DiskSizeBytes, o.DiskReadBytes, o.DiskWriteBytes, o.DiskIOInflight = nil, nil, nil, nil
+		return
+	}
+	read, write, inflight := int64(st.Snapshot.DiskReadTotal), int64(st.Snapshot.DiskWriteTotal), st.Snapshot.DiskIOInflight
+	o.DiskReadBytes, o.DiskWriteBytes, o.DiskIOInflight = &read, &write, &inflight
+
+	o.DiskSizeBytes = nil
+	if size := st.Snapshot.DiskSpaceTotal; size > 0 && size <= math.MaxInt64 {
+		o.DiskSizeBytes = ptr.To(int64(size))
+	}
+}
+
+// observeSize records the CPUs and memory of the supplied VM from its stats.
+// The fields are cleared when the stats do not report them.
+func observeSize(cr *v1alpha1.VM, st *sdk.SlicerNodeStat) {
+	o := &cr.Status.AtProvider
+	o.CPUs, o.MemoryBytes = 0, nil
+	if st == nil || st.Snapshot == nil {
+		return
+	}
+	o.CPUs = st.Snapshot.TotalCPUS
+	if m := st.Snapshot.TotalMemory; m > 0 && m <= math.MaxInt64 {
+		o.MemoryBytes = ptr.To(int64(m))
+	}
+}
+
+// sizeDrift describes how the observed size of the supplied VM differs from
+// the size it was requested with. It returns an empty string if the sizes
+// match, or the size was not observed.
+func (e *external) sizeDrift(cr *v1alpha1.VM) string {
+	o := cr.Status.AtProvider
+	var drift []string
+	if cpus := e.cpusFor(cr); o.CPUs > 0 && o.CPUs != cpus {
+		drift = append(drift, fmt.Sprintf("VM has %d CPUs but %d are requested", o.CPUs, cpus))
+	}
+	if o.MemoryBytes != nil {
+		if ram := e.ramGBFor(cr); !ramMatches(*o.MemoryBytes, ram) {
+			drift = append(drift, fmt.Sprintf("VM has %d GB of RAM but %d GB are requested", ramGBOf(*o.MemoryBytes), ram))
+		}
+	}
+	if len(drift) == 0 {
+		return ""
+	}
+	return strings.Join(drift, "; ") + "; the VM must be recreated for its size to change"
+}
+
+// The guest reports less memory than its VM was created with, as its kernel
+// reserves some. The reserved memory grows with the VM's memory, and is
+// assumed to be less than the larger of ramReserveFraction of it and
+// ramReserveMin.
+const (
+	ramReserveFraction = 0.05
+	ramReserveMin      = 1 << 30
+)
+
+// ramMatches returns true if the supplied memory reported by a guest is that
+// of a VM created with the supplied GB of RAM.
+func ramMatches(memoryBytes int64, gb int) bool {
+	want := float64(gb) * (1 << 30)
+	reserve := max(want*ramReserveFraction, ramReserveMin)
+	got := float64(memoryBytes)
+	return got <= want && got > want-reserve
+}
+
+// ramGBOf returns the GB of RAM of a VM whose guest reports the supplied
+// memory. It is rounded up, as the guest reports less than the VM has.
+func ramGBOf(memoryBytes int64) int {
+	return int(math.Ceil(float64(memoryBytes) / (1 << 30)))
+}
+
+// probe records whether the supplied VM accepts TCP connections on its SSH
+// port, and how long connecting took. The probe is bounded by probeTimeout and
+// the observe timeout, so it never blocks a reconcile for long.
+func (e *external) probe(ctx context.Context, cr *v1alpha1.VM, ip string) {
+	o := &cr.Status.AtProvider
+	addr, ok := parseIP(ip)
+	if !ok {
+		o.Reachable, o.Latency = ptr.To(false), nil
+		return
+	}
+	port := e.sshPort()
+
+	ctx, cancel := context.WithTimeout(ctx, min(probeTimeout, e.observeTimeout))
+	defer cancel()
+	start := time.Now()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", netip.AddrPortFrom(addr, uint16(port)).String()) //nolint:gosec // Ports are validated to fit.
+	if err != nil {
+		o.Reachable, o.Latency = ptr.To(false), nil
+		return
+	}
+	_ = conn.Close()
+	o.Reachable, o.Latency = ptr.To(true), &metav1.Duration{Duration: time.Since(start)}
+}
+
+// checkHealth runs the health check of the supplied VM against the supplied IP
+// address, and records the result. VMs without a health check, or that are not
+// running, are not checked. The check is bounded by its timeout and the
+// observe timeout.
+func (e *external) checkHealth(ctx context.Context, cr *v1alpha1.VM, ip string) {
+	o := &cr.Status.AtProvider
+	hc := cr.Spec.ForProvider.HealthCheck
+	if hc == nil || o.State != stateRunning {
+		o.HealthCheckPassing, o.HealthCheckError = nil, ""
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, min(durationOr(hc.Timeout, probeTimeout), e.observeTimeout))
+	defer cancel()
+	if err := runHealthCheck(ctx, hc, ip); err != nil {
+		o.HealthCheckPassing, o.HealthCheckError = ptr.To(false), err.Error()
+		return
+	}
+	o.HealthCheckPassing, o.HealthCheckError = ptr.To(true), ""
+}
+
+// runHealthCheck returns an error if the supplied health check fails against
+// the supplied IP address, which may be in CIDR notation.
+func runHealthCheck(ctx context.Context, hc *v1alpha1.HealthCheck, ip string) error {
+	addr, ok := parseIP(ip)
+	if !ok {
+		return errors.New("VM has no IP address")
+	}
+	target := netip.AddrPortFrom(addr, uint16(hc.Port)).String() //nolint:gosec // Ports are validated to fit.
+
+	if hc.Protocol != v1alpha1.HealthCheckHTTP {
+		conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", target)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	path := hc.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+target+path, nil)
+	if err != nil {
+		return err
+	}
+	// Redirects are not followed, as they may lead away from the VM.
+	hcl := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := hcl.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("GET %s returned %s", path, resp.Status)
+	}
+	return nil
+}
+
+// healthCheckFailing returns true if the health check of the supplied VM ran
+// and failed.
+func healthCheckFailing(cr *v1alpha1.VM) bool {
+	p := cr.Status.AtProvider.HealthCheckPassing
+	return p != nil && !*p
+}
+
+// isPublicIP returns true if the supplied IP address, which may be in CIDR
+// notation, is publicly routable.
+func isPublicIP(ip string) bool {
+	addr, ok := parseIP(ip)
+	return ok && addr.IsGlobalUnicast() && !addr.IsPrivate()
+}
+
+// parseIP parses an IP address that may be in CIDR notation, as returned by
+// the Slicer API.
+func parseIP(ip string) (netip.Addr, bool) {
+	if p, err := netip.ParsePrefix(ip); err == nil {
+		return p.Addr(), true
+	}
+	addr, err := netip.ParseAddr(ip)
+	return addr, err == nil
+}
+
+// sshConfigured returns true if the VM is configured to accept SSH logins.
+func sshConfigured(cr *v1alpha1.VM) bool {
+	p := cr.Spec.ForProvider
+	return len(p.SSHKeys) > 0 || len(p.SSHKeySecretRefs) > 0 || p.ImportUser != ""
+}
+
+// rebootRequested returns true if the reboot annotation holds a value that has
+// not yet been acted upon.
+func rebootRequested(cr *v1alpha1.VM) bool {
+	req := cr.GetAnnotations()[annotationReboot]
+	return req != "" && req != cr.Status.AtProvider.LastRebootRequest
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*v1alpha1.VM)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotVM)
+	}
+
+	cr.SetConditions(xpv1.Creating())
+
+	if err := e.giveUpCreating(cr); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	hostGroup, err := e.createHostGroupFor(ctx, cr)
+	if err != nil {
+		return createFailed(cr, err)
+	}
+	req, err := e.createRequest(ctx, cr)
+	if err != nil {
+		return createFailed(cr, err)
+	}
+
+	// The Slicer API may ignore the idempotency key, so look for a VM created
+	// by an earlier attempt before creating another. Its result may have been
+	// lost, for example because the create timed out or the provider
+	// restarted before the resource's external name was persisted.
+	earlier, earlierHostGroup, err := e.findNode(ctx, cr, hostGroup, func(n *sdk.SlicerNode) bool { return slices.Contains(n.Tags, ownerTag(cr)) })
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	if earlier != nil {
+		e.logger.Debug("Found VM created earlier", "hostname", earlier.Hostname, "host-group", earlierHostGroup, "ip", earlier.IP)
+		e.recorder.Event(cr, event.Normal(reasonCreated, fmt.Sprintf("Found VM %s created earlier in host group %s", earlier.Hostname, earlierHostGroup)))
+		return e.created(cr, earlierHostGroup, req, &sdk.SlicerCreateNodeResponse{
+			Hostname:  earlier.Hostname,
+			IP:        earlier.IP,
+			CreatedAt: earlier.CreatedAt,
+			Arch:      earlier.Arch,
+		}), nil
+	}
+
+	// Waiting for a free slot does not count as a failed attempt. The VM is
+	// requeued rather than holding a worker while it waits.
+	if e.maxCreates > 0 {
+		if !e.creates.acquire(e.nodesKey(hostGroup), e.maxCreates) {
+			return managed.ExternalCreation{}, errors.Errorf(errTooManyCreates, e.maxCreates, hostGroup)
+		}
+		defer e.creates.release(e.nodesKey(hostGroup))
+	}
+
+	// Record the host group before creating the VM, so that a create that
+	// is retried after a timeout, when the VM may have been created, stays
+	// in the same host group even if another would now be selected.
+	cr.Status.AtProvider.HostGroup = hostGroup
+
+	// Create VM. The client sends an idempotency key with the request.
+	e.logger.Debug("Creating VM", "host-group", hostGroup, "operation", metrics.OperationCreateNode, "idempotency-key", idempotencyKey(cr))
+	createCtx, cancel := context.WithTimeout(ctx, e.createTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := e.client.CreateNode(createCtx, hostGroup, req)
+	e.calls.Observe(metrics.OperationCreateNode, hostGroup, start, err)
+	e.nodes.Forget(e.nodesKey(hostGroup))
+	err = e.apiErrors.wrap(err)
+	switch {
+	case isTimeout(err):
+		// The VM may still be being created. Retrying the create is safe as
+		// it carries the same idempotency key, so a timeout does not count
+		// as a failed attempt.
+		return managed.ExternalCreation{}, errors.Wrapf(err, errCreateTimeout, e.createTimeout)
+	case isRateLimited(err):
+		// Nor does being asked to back off.
+		return managed.ExternalCreation{}, errors.Wrap(err, "cannot create VM")
+	case isNotFound(err):
+		return createFailed(cr, errors.Errorf(errHostGroupNotFound, hostGroup))
+	case err != nil:
+		return createFailed(cr, errors.Wrap(err, "cannot create VM"))
+	}
+
+	e.logger.Debug("Created VM", "hostname", resp.Hostname, "host-group", hostGroup, "ip", resp.IP)
+	e.recorder.Event(cr, event.Normal(reasonCreated, fmt.Sprintf("Created VM %s in host group %s", resp.Hostname, hostGroup)))
+	return e.created(cr, hostGroup, req, resp), nil
+}
+
+// created records that the supplied VM was created in the supplied host group
+// using the supplied request, and returns the result of the create.
+func (e *external) created(cr *v1alpha1.VM, hostGroup string, req sdk.SlicerCreateNodeRequest, resp *sdk.SlicerCreateNodeResponse) managed.ExternalCreation {
+	// Set external name to hostname
+	meta.SetExternalName(cr, resp.Hostname)
+
+	// Update status
+	cr.Status.AtProvider.Hostname = resp.Hostname
+	cr.Status.AtProvider.HostGroup = hostGroup
+	cr.Status.AtProvider.IP = resp.IP
+	cr.Status.AtProvider.Arch = resp.Arch
+	cr.Status.AtProvider.CloudInitDone = false
+	cr.Status.AtProvider.CreatedAt = resp.CreatedAt.String()
+	cr.Status.AtProvider.CreateParametersHash = e.createParametersHash(cr)
+	cr.Status.AtProvider.CreateAttempts = 0
+	cr.Status.AtProvider.LastCreateError = ""
+	cr.Status.AtProvider.CreateFailingSince = nil
+	if cr.GetCondition(v1alpha1.TypeCreateRetriesExhausted).Status != corev1.ConditionUnknown {
+		cr.SetConditions(v1alpha1.CreateRetrying())
+	}
+
+	// A freshly created VM does not need to honour a reboot request that
+	// predates it.
+	cr.Status.AtProvider.LastRebootRequest = cr.GetAnnotations()[annotationReboot]
+
+	// The cluster connection secret is published by the next observation,
+	// so that a failure to publish it cannot fail a create that succeeded.
+	return managed.ExternalCreation{
+		ConnectionDetails: e.connectionDetailsFor(resp.Hostname, resp.IP, req.Tags),
+	}
+}
+
+// findNode returns the first VM matching the supplied function, and its host
+// group. It looks in the supplied host group or, while the supplied VM
+// resource's host group is being selected, in every candidate. Missing host
+// groups are skipped; creates leave them for the create to report.
+func (e *external) findNode(ctx context.Context, cr *v1alpha1.VM, hostGroup string, match func(n *sdk.SlicerNode) bool) (*sdk.SlicerNode, string, error) {
+	hostGroups := []string{hostGroup}
+	if e.selectingHostGroup(cr) {
+		hostGroups = e.hostGroupSelection.HostGroups
+	}
+	for _, hg := range hostGroups {
+		nodes, err := e.listNodes(ctx, hg)
+		switch {
+		case isNotFound(err):
+			continue
+		case err != nil:
+			return nil, "", errors.Wrap(err, "cannot list VMs")
+		}
+		for i := range nodes {
+			if match(&nodes[i]) {
+				return &nodes[i], hg, nil
+			}
+		}
+	}
+	return nil, "", nil
+}
+
+// createRequest returns the request creating the supplied VM, or an error if
+// its parameters are invalid or cannot be resolved.
+func (e *external) createRequest(ctx context.Context, cr *v1alpha1.VM) (sdk.SlicerCreateNodeRequest, error) {
+	req := sdk.SlicerCreateNodeRequest{
+		RamGB: e.ramGBFor(cr),
+		CPUs:  e.cpusFor(cr),
+	}
+
+	// A VM that does not fit on a single host can never be created.
+	if e.maxCPUs > 0 && req.CPUs > e.maxCPUs {
+		return sdk.SlicerCreateNodeRequest{}, errors.Errorf(errExceedsMaxCPUs, req.CPUs, e.maxCPUs)
+	}
+	if e.maxRAMGB > 0 && req.RamGB > e.maxRAMGB {
+		return sdk.SlicerCreateNodeRequest{}, errors.Errorf(errExceedsMaxRAMGB, req.RamGB, e.maxRAMGB)
+	}
+
+	userdata, err := e.resolveUserdata(ctx, cr)
+	if err != nil {
+		return sdk.SlicerCreateNodeRequest{}, err
+	}
+	req.Userdata = userdata
+
+	keys, err := e.resolveSSHKeys(ctx, cr)
+	if err != nil {
+		return sdk.SlicerCreateNodeRequest{}, err
+	}
+	if len(keys) > 0 {
+		req.SSHKeys = dedupeSSHKeys(keys)
+	}
+
+	if cr.Spec.ForProvider.ImportUser != "" {
+		req.ImportUser = cr.Spec.ForProvider.ImportUser
+	}
+
+	tags, err := e.applyTagPolicies(ctx, cr)
+	if err != nil {
+		return sdk.SlicerCreateNodeRequest{}, err
+	}
+	req.Tags = append(tags, ownerTag(cr))
+
+	// Extra fields are merged into the request body by the client transport.
+	if err := validateExtraFields(cr.Spec.ForProvider); err != nil {
+		return sdk.SlicerCreateNodeRequest{}, err
+	}
+	return req, nil
+}
+
+// resolveUserdata returns the userdata of the supplied VM. Inline userdata
+// takes precedence over userdata read from a ConfigMap or Secret.
+func (e *external) resolveUserdata(ctx context.Context, cr *v1alpha1.VM) (string, error) {
+	p := cr.Spec.ForProvider
+	if p.Userdata != "" || p.UserdataFrom == nil {
+		return p.Userdata, nil
+	}
+
+	if ref := p.UserdataFrom.SecretKeyRef; ref != nil {
+		s := &corev1.Secret{}
+		if err := e.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: ref.Name}, s); err != nil {
+			return "", errors.Wrapf(err, errGetUserdataSecret, ref.Name)
+		}
+		data, ok := s.Data[ref.Key]
+		if !ok {
+			return "", errors.Errorf(errMissingUserdataSecretKey, ref.Name, ref.Key)
+		}
+		return string(data), nil
+	}
+
+	if ref := p.UserdataFrom.ConfigMapKeyRef; ref != nil {
+		cm := &corev1.ConfigMap{}
+		if err := e.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: ref.Name}, cm); err != nil {
+			return "", errors.Wrapf(err, errGetUserdataConfigMap, ref.Name)
+		}
+		if data, ok := cm.Data[ref.Key]; ok {
+			return data, nil
+		}
+		if data, ok := cm.BinaryData[ref.Key]; ok {
+			return string(data), nil
+		}
+		return "", errors.Errorf(errMissingUserdataConfigMapKey, ref.Name, ref.Key)
+	}
+	return "", nil
+}
+
+// resolveSSHKeys returns the SSH public keys of the supplied VM: its inline
+// keys followed by those read from its SSH key secret references.
+func (e *external) resolveSSHKeys(ctx context.Context, cr *v1alpha1.VM) ([]string, error) {
+	keys := append([]string{}, cr.Spec.ForProvider.SSHKeys...)
+	for _, ref := range cr.Spec.ForProvider.SSHKeySecretRefs {
+		s := &corev1.Secret{}
+		if err := e.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: ref.Name}, s); err != nil {
+			return nil, errors.Wrapf(err, errGetSSHKeySecret, ref.Name)
+		}
+		data, ok := s.Data[ref.Key]
+		if !ok {
+			return nil, errors.Errorf(errMissingSSHKey, ref.Name, ref.Key)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if k := strings.TrimSpace(line); k != "" && !strings.HasPrefix(k, "#") {
+				keys = append(keys, k)
+			}
+		}
+	}
+	return keys, nil
+}
+
+// cpusFor returns the number of CPUs of the supplied VM, or the provider
+// config's default if it does not specify one.
+func (e *external) cpusFor(cr *v1alpha1.VM) int {
+	if cpus := cr.Spec.ForProvider.CPUs; cpus != 0 {
+		return cpus
+	}
+	return e.defaultCPUs
+}
+
+// ramGBFor returns the RAM in GB of the supplied VM, or the provider config's
+// default if it does not specify it.
+func (e *external) ramGBFor(cr *v1alpha1.VM) int {
+	if gb := cr.Spec.ForProvider.RAMGB; gb != 0 {
+		return gb
+	}
+	return e.defaultRAMGB
+}
+
+// applyTagPolicies returns the tags of the supplied VM with the default tags of
+// all TagPolicies added. It returns an error, and sets a TagPolicyViolation
+// condition, if the VM lacks tags the policies require.
+func (e *external) applyTagPolicies(ctx context.Context, cr *v1alpha1.VM) ([]string, error) {
+	policies, err := e.tagPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := e.desiredTags(cr, policies)
+	keys := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		keys[tagKey(t)] = true
+	}
+
+	missing := map[string]bool{}
+	for _, p := range policies {
+		for _, k := range p.Spec.RequiredTagKeys {
+			if !keys[k] {
+				missing[k] = true
+			}
+		}
+	}
+	if len(missing) > 0 {
+		m := make([]string, 0, len(missing))
+		for k := range missing {
+			m = append(m, k)
+		}
+		sort.Strings(m)
+		err := errors.Errorf(errMissingTags, strings.Join(m, ", "))
+		cr.SetConditions(v1alpha1.TagPolicyViolation().WithMessage(err.Error()))
+		return nil, err
+	}
+	if cr.GetCondition(v1alpha1.TypeTagPolicyViolation).Status != corev1.ConditionUnknown {
+		cr.SetConditions(v1alpha1.TagPolicySatisfied())
+	}
+	return tags, nil
+}
+
+// tagPolicies returns all TagPolicies, sorted by name.
+func (e *external) tagPolicies(ctx context.Context) ([]v1alpha1.TagPolicy, error) {
+	l := &v1alpha1.TagPolicyList{}
+	if err := e.kube.List(ctx, l); err != nil {
+		return nil, errors.Wrap(err, errListTagPolicies)
+	}
+	sort.Slice(l.Items, func(i, j int) bool { return l.Items[i].GetName() < l.Items[j].GetName() })
+	return l.Items, nil
+}
+
+// desiredTags returns the tags the supplied VM should be created with: its own
+// tags, then tags of its metadata entries, then its metadata tags and then the
+// default tags of the supplied TagPolicies, each added only for keys the VM
+// lacks.
+func (e *external) desiredTags(cr *v1alpha1.VM, policies []v1alpha1.TagPolicy) []string {
+	tags := withTags(cr.Spec.ForProvider.Tags, keyValueTags(cr.Spec.ForProvider.Metadata))
+	tags = withTags(tags, e.metadataTagsFor(cr))
+	for _, p := range policies {
+		tags = withTags(tags, p.Spec.DefaultTags)
+	}
+	return tags
+}
+
+// withTags returns the supplied tags with the supplied additional tags added
+// for any keys they lack.
+func withTags(tags, add []string) []string {
+	out := append([]string{}, tags...)
+	keys := make(map[string]bool, len(out))
+	for _, t := range out {
+		keys[tagKey(t)] = true
+	}
+	for _, t := range add {
+		if !keys[tagKey(t)] {
+			out = append(out, t)
+			keys[tagKey(t)] = true
+		}
+	}
+	return out
+}
+
+// metadataTagsFor returns the tags derived from the metadata of the supplied
+// VM, if metadata tags are enabled. Labels and annotations are mapped in the
+// order of their tag keys, so the tags are stable.
+func (e *external) metadataTagsFor(cr *v1alpha1.VM) []string {
+	if e.metadataTags == nil {
+		return nil
+	}
+	tags := []string{tagK8sName + "=" + cr.GetName(), tagK8sNamespace + "=" + cr.GetNamespace()}
+	var mapped []string
+	for from, to := range e.metadataTags.Labels {
+		if v, ok := cr.GetLabels()[from]; ok {
+			mapped = append(mapped, to+"="+v)
+		}
+	}
+	for from, to := range e.metadataTags.Annotations {
+		if v, ok := cr.GetAnnotations()[from]; ok {
+			mapped = append(mapped, to+"="+v)
+		}
+	}
+	sort.Strings(mapped)
+	return append(tags, mapped...)
+}
+
+// tagDrift describes how the supplied observed tags of a VM differ from the
+// tags it should have: its own tags plus its metadata tags and the default
+// tags of all TagPolicies. The owner tag is ignored. It returns an empty string if the
+// tags match.
+func (e *external) tagDrift(ctx context.Context, cr *v1alpha1.VM, observed []string) (string, error) {
+	policies, err := e.tagPolicies(ctx)
+	if err != nil {
+		return "", err
+	}
+	want := map[string]bool{}
+	for _, t := range e.desiredTags(cr, policies) {
+		want[t] = true
+	}
+	have := map[string]bool{}
+	for _, t := range observed {
+		if !strings.HasPrefix(t, tagOwnerPrefix) {
+			have[t] = true
+		}
+	}
+
+	var missing, unexpected []string
+	for t := range want {
+		if !have[t] {
+			missing = append(missing, t)
+		}
+	}
+	for t := range have {
+		if !want[t] {
+			unexpected = append(unexpected, t)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+
+	var drift []string
+	if len(missing) > 0 {
+		drift = append(drift, "missing tags: "+strings.Join(missing, ", "))
+	}
+	if len(unexpected) > 0 {
+		drift = append(drift, "unexpected tags: "+strings.Join(unexpected, ", "))
+	}
+	return strings.Join(drift, "; "), nil
+}
+
+// keyValueTags returns the supplied key/value metadata as key=value tags, in
+// the order of their keys.
+func keyValueTags(m map[string]string) []string {
+	tags := make([]string, 0, len(m))
+	for k, v := range m {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// metadataOf returns the key/value metadata of a VM with the supplied tags:
+// its key=value tags, except the owner tag. It returns nil if there are none.
+func metadataOf(tags []string) map[string]string {
+	var m map[string]string
+	for _, t := range tags {
+		k, v, ok := strings.Cut(t, "=")
+		if !ok || strings.HasPrefix(t, tagOwnerPrefix) {
+			continue
+		}
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// tagKey returns the key of the supplied tag: the part before its first "=",
+// or the whole tag if it has none.
+func tagKey(tag string) string {
+	k, _, _ := strings.Cut(tag, "=")
+	return k
+}
+
+// dedupeSSHKeys returns the supplied SSH public keys without duplicates. Keys
+// are compared by type and key material only, so keys differing only in their
+// comment or surrounding whitespace are duplicates. The first occurrence of
+// each key is kept in its original form.
+func dedupeSSHKeys(keys []string) []string {
+	seen := make(map[string]bool, len(keys))
+	out := make([]string, 0, len(keys))
+	for _, k := range keys {
+		f := strings.Fields(k)
+		if len(f) == 0 {
+			continue
+		}
+		id := strings.Join(f[:min(len(f), 2)], " ")
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		out = append(out, k)
+	}
+	return out
+}
+
+// isTimeout returns true if the supplied error was caused by a Slicer API call
+// timing out.
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// createFailed records a failed create attempt in the status of the supplied
+// VM, which is persisted even though the create returns an error.
+func createFailed(cr *v1alpha1.VM, err error) (managed.ExternalCreation, error) {
+	cr.Status.AtProvider.CreateAttempts++
+	cr.Status.AtProvider.LastCreateError = err.Error()
+	if cr.Status.AtProvider.CreateFailingSince == nil {
+		now := metav1.Now()
+		cr.Status.AtProvider.CreateFailingSince = &now
+	}
+	return managed.ExternalCreation{}, err
+}
+
+// giveUpCreating returns an error if creating the supplied VM has failed for
+// longer than the create retry limit, in which case no create is attempted and
+// a CreateRetriesExhausted condition is set. Once the cooldown has passed the
+// failed attempts are forgotten, and creating the VM is retried.
+func (e *external) giveUpCreating(cr *v1alpha1.VM) error {
+	since := cr.Status.AtProvider.CreateFailingSince
+	if e.createRetryLimit <= 0 || since == nil || time.Since(since.Time) < e.createRetryLimit {
+		return nil
+	}
+
+	c := cr.GetCondition(v1alpha1.TypeCreateRetriesExhausted)
+	if c.Status != corev1.ConditionTrue {
+		err := errors.Errorf(errCreateGaveUp, e.createRetryLimit, e.createRetryCooldown)
+		cr.SetConditions(v1alpha1.CreateRetriesExhausted().WithMessage(cr.Status.AtProvider.LastCreateError))
+		e.recorder.Event(cr, event.Warning(reasonCreateGaveUp, err))
+		return err
+	}
+	if resume := c.LastTransitionTime.Add(e.createRetryCooldown); time.Now().Before(resume) {
+		return errors.Errorf(errCreateGaveUp, e.createRetryLimit, time.Until(resume).Round(time.Second))
+	}
+
+	cr.Status.AtProvider.CreateAttempts = 0
+	cr.Status.AtProvider.LastCreateError = ""
+	cr.Status.AtProvider.CreateFailingSince = nil
+	cr.SetConditions(v1alpha1.CreateRetrying())
+	return nil
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.VM)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotVM)
+	}
+
+	// Slicer VMs cannot be updated in place, only recreated. The only
+	// in-place action supported is a reboot.
+	if rebootRequested(cr) {
+		rebootCtx, cancel := context.WithTimeout(ctx, e.updateTimeout)
+		defer cancel()
+		if err := e.exec(rebootCtx, cr.Status.AtProvider.HostGroup, meta.GetExternalName(cr), "reboot"); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errRebootVM)
+		}
+		e.recorder.Event(cr, event.Normal(reasonRebooted, fmt.Sprintf("Rebooted VM %s", meta.GetExternalName(cr))))
+		now := metav1.Now()
+		cr.Status.AtProvider.LastRebootRequest = cr.GetAnnotations()[annotationReboot]
+		cr.Status.AtProvider.LastRebootTime = &now
+	}
+
+	if !e.recreateRequired(cr) {
+		// A VM whose size differs from its parameters although they have
+		// not changed is never recreated automatically, as that would
+		// loop if the size is misreported.
+		if c := cr.GetCondition(v1alpha1.TypeRecreateRequired); c.Status == corev1.ConditionTrue {
+			err := errors.New(c.Message)
+			e.recorder.Event(cr, event.Warning(reasonRecreateRequired, err))
+			return managed.ExternalUpdate{}, err
+		}
+		return managed.ExternalUpdate{}, nil
+	}
+
+	// Never report a change that could not be applied as synced.
+	if e.updatePolicy != apisv1alpha1.UpdatePolicyRecreate {
+		e.recorder.Event(cr, event.Warning(reasonRecreateRequired, errors.New(errRecreateRequired)))
+		return managed.ExternalUpdate{}, errors.New(errRecreateRequired)
+	}
+
+	// Once the VM is gone it is observed as missing and created again with
+	// the changed parameters.
+	e.recorder.Event(cr, event.Normal(reasonRecreating, "Deleting VM to recreate it with changed parameters"))
+	if err := e.deleteVM(ctx, cr, meta.GetExternalName(cr)); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errRecreateVM)
+	}
+	return managed.ExternalUpdate{}, nil
+}
+
+// createParametersHash returns a hash of the parameters of the supplied VM
+// that can only be set when it is created. Parameters added later are omitted
+// when unset, so that the hashes of existing VMs do not change. An unset size
+// is hashed as the provider config's default it is created with, so late
+// initializing it does not require the VM to be recreated.
+func (e *external) createParametersHash(cr *v1alpha1.VM) string {
+	p := cr.Spec.ForProvider
+	// Marshalling these fields cannot fail.
+	b, _ := json.Marshal(struct {
+		CPUs        int                   `json:"cpus"`
+		RAMGB       int                   `json:"ramGb"`
+		Userdata    string                `json:"userdata"`
+		SSHKeys     []string              `json:"sshKeys"`
+		ImportUser  string                `json:"importUser"`
+		ExtraFields map[string]extv1.JSON `json:"extraFields"`
+
+		SSHKeySecretRefs []xpv1.LocalSecretKeySelector `json:"sshKeySecretRefs,omitempty"`
+		UserdataFrom     *v1alpha1.UserdataSource      `json:"userdataFrom,omitempty"`
+	}{e.cpusFor(cr), e.ramGBFor(cr), p.Userdata, p.SSHKeys, p.ImportUser, p.ExtraFields, p.SSHKeySecretRefs, p.UserdataFrom})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// recreateRequired returns true if parameters of the supplied VM that can only
+// be set when it is created have changed since it was created.
+func (e *external) recreateRequired(cr *v1alpha1.VM) bool {
+	h := cr.Status.AtProvider.CreateParametersHash
+	return h != "" && h != e.createParametersHash(cr)
+}
+
+// exec runs the supplied command on the supplied VM using its guest agent. It
+// is used for commands that take the VM down, like reboot.
+func (e *external) exec(ctx context.Context, hostGroup, hostname, command string) error {
+	start := time.Now()
+	res, err := e.client.Exec(ctx, hostname, sdk.SlicerExecRequest{Command: command})
+	e.calls.Observe(metrics.OperationExec, hostGroup, start, err)
+	if err != nil {
+		return err
+	}
+	// The output stream is usually cut short by the guest going down, so
+	// errors reading it are expected. Drain it so the SDK's reader exits.
+	for range res {
+	}
+	return nil
+}
+
+// cloudInitReadiness returns the Ready condition of the supplied running VM
+// while cloud-init has not finished on it, or nil once it has. The VM is
+// creating until cloud-init finishes, and unavailable if cloud-init failed or
+// did not finish within the cloud-init timeout. Once cloud-init has finished
+// it is not checked again.
+func (e *external) cloudInitReadiness(ctx context.Context, cr *v1alpha1.VM, found *sdk.SlicerNode) *xpv1.Condition {
+	if cr.Status.AtProvider.CloudInitDone {
+		return nil
+	}
+
+	status, err := e.cloudInitStatus(ctx, cr.Status.AtProvider.HostGroup, found.Hostname)
+	if err != nil {
+		e.logger.Debug("Cannot read cloud-init status", "hostname", found.Hostname, "error", err)
+	}
+	var c xpv1.Condition
+	switch {
+	case status == "done" || status == "disabled":
+		cr.Status.AtProvider.CloudInitDone = true
+		return nil
+	case status == "error":
+		c = xpv1.Unavailable().WithMessage(errCloudInitFailed)
+	case time.Since(found.CreatedAt) >= e.cloudInitTimeout:
+		c = xpv1.Unavailable().WithMessage(fmt.Sprintf(errCloudInitTimeout, e.cloudInitTimeout))
+	default:
+		c = xpv1.Creating().WithMessage("waiting for cloud-init to finish")
+	}
+	return &c
+}
+
+// cloudInitStatus returns the status cloud-init reports on the supplied VM,
+// for example running, done or error. It runs cloud-init status in the VM,
+// bounded by the observe timeout.
+func (e *external) cloudInitStatus(ctx context.Context, hostGroup, hostname string) (string, error) {
+	execCtx, cancel := context.WithTimeout(ctx, e.observeTimeout)
+	defer cancel()
+	start := time.Now()
+	res, err := e.client.Exec(execCtx, hostname, sdk.SlicerExecRequest{Command: "cloud-init", Args: []string{"status"}})
+	e.calls.Observe(metrics.OperationExec, hostGroup, start, err)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for r := range res {
+		if r.Error != "" {
+			err = errors.New(r.Error)
+		}
+		out.WriteString(r.Stdout)
+	}
+	if err != nil {
+		return "", err
+	}
+	for line := range strings.Lines(out.String()) {
+		if s, ok := strings.CutPrefix(strings.TrimSpace(line), "status:"); ok {
+			return strings.TrimSpace(s), nil
+		}
+	}
+	return "", errors.Errorf("unexpected cloud-init status output %q", out.String())
+}
+
+// Delete deletes the VM. The connection secret needs no cleanup here: it is
+// always written to the VM's namespace with the VM as its controller owner, so
+// Kubernetes garbage collects it once the VM is gone. Only the cluster
+// connection secret, which cannot be owned by the VM, is deleted explicitly.
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*v1alpha1.VM)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotVM)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	// Get external name (hostname)
+	externalName := meta.GetExternalName(cr)
+	if externalName == "" {
+		return managed.ExternalDelete{}, nil
+	}
+
+	if cr.Spec.ForProvider.ShutdownBeforeDelete && !e.shutDown(ctx, cr, externalName) {
+		// The VM is deleted by a later reconcile, once it has shut down.
+		return managed.ExternalDelete{}, nil
+	}
+
+	if err := e.deleteVM(ctx, cr, externalName); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, "cannot delete VM")
+	}
+	e.metrics.Delete(types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()})
+	e.recorder.Event(cr, event.Normal(reasonDeleted, fmt.Sprintf("Deleted VM %s", externalName)))
+
+	if err := e.unpublishClusterSecret(ctx, cr); err != nil {
+		return managed.ExternalDelete{}, err
+	}
+
+	return managed.ExternalDelete{}, nil
+}
+
+// shutDown asks the guest of the supplied VM to shut down, and returns true
+// once it has, or has not within the shutdown timeout. A VM has shut down when
+// its guest agent stops reporting. A VM whose guest cannot be asked to shut
+// down is deleted right away.
+func (e *external) shutDown(ctx context.Context, cr *v1alpha1.VM, hostname string) bool {
+	o := &cr.Status.AtProvider
+	if o.ShutdownRequestedAt == nil {
+		shutdownCtx, cancel := context.WithTimeout(ctx, e.deleteTimeout)
+		defer cancel()
+		if err := e.exec(shutdownCtx, o.HostGroup, hostname, "poweroff"); err != nil {
+			e.recorder.Event(cr, event.Warning(reasonShutdownFailed, errors.Wrap(err, errShutdownVM)))
+			return true
+		}
+		now := metav1.Now()
+		o.ShutdownRequestedAt = &now
+		e.recorder.Event(cr, event.Normal(reasonShuttingDown, fmt.Sprintf("Shutting down VM %s before deleting it", hostname)))
+		return false
+	}
+
+	if st := e.statsFor(ctx, o.HostGroup, hostname); st == nil || st.Error != "" || st.Snapshot == nil {
+		return true
+	}
+	if time.Since(o.ShutdownRequestedAt.Time) < e.shutdownTimeout {
+		return false
+	}
+	e.recorder.Event(cr, event.Warning(reasonShutdownFailed, errors.Errorf(errShutdownTimeout, e.shutdownTimeout)))
+	return true
+}
+
+// deleteVM deletes the supplied VM from the host group it was created in. A VM
+// that no longer exists is considered deleted.
+func (e *external) deleteVM(ctx context.Context, cr *v1alpha1.VM, hostname string) error {
+	hostGroup := cr.Status.AtProvider.HostGroup
+	if hostGroup == "" {
+		hostGroup = e.hostGroupFor(cr)
+	}
+
+	e.logger.Debug("Deleting VM", "hostname", hostname, "host-group", hostGroup, "operation", metrics.OperationDeleteVM)
+	deleteCtx, cancel := context.WithTimeout(ctx, e.deleteTimeout)
+	defer cancel()
+	start := time.Now()
+	_, err := e.client.DeleteVM(deleteCtx, hostGroup, hostname)
+	e.calls.Observe(metrics.OperationDeleteVM, hostGroup, start, err)
+	e.nodes.Forget(e.nodesKey(hostGroup))
+	// The VM may have been deleted out of band since it was observed.
+	if err = e.apiErrors.wrap(err); isNotFound(err) {
+		e.logger.Debug("VM was already deleted", "hostname", hostname, "host-group", hostGroup)
+		return nil
+	}
+	return err
+}
+
+// Disconnect closes the idle connections of a transport made for this
+// connection, which is not reused by later reconciles. The shared default
+// transport keeps its connections so that later reconciles can reuse them.
+func (e *external) Disconnect(ctx context.Context) error {
+	if e.transport != http.DefaultTransport {
+		e.transport.CloseIdleConnections()
+	}
 	return nil
 }
@@ -0,0 +1,1351 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	sdk "github.com/slicervm/sdk"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+// Unlike many Kubernetes projects Crossplane does not use third party testing
+// libraries, per the common Go test review comments. Crossplane encourages the
+// use of table driven unit tests. The tests of the crossplane-runtime project
+// are representative of the testing style Crossplane encourages.
+//
+// https://github.com/golang/go/wiki/TestComments
+// https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
+
+// fakeSlicer is a slicerAPI whose calls are answered by its mock functions.
+// Calls without a mock function succeed with an empty result.
+type fakeSlicer struct {
+	MockGetHostGroupNodes func(ctx context.Context, groupName string) ([]sdk.SlicerNode, error)
+	MockCreateNode        func(ctx context.Context, groupName string, request sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error)
+	MockDeleteVM          func(ctx context.Context, groupName, hostname string) (*sdk.SlicerDeleteResponse, error)
+	MockGetVMStats        func(ctx context.Context, hostname string) ([]sdk.SlicerNodeStat, error)
+	MockExec              func(ctx context.Context, nodeName string, execReq sdk.SlicerExecRequest) (chan sdk.SlicerExecWriteResult, error)
+}
+
+var _ slicerAPI = &fakeSlicer{}
+
+func (f *fakeSlicer) GetHostGroupNodes(ctx context.Context, groupName string) ([]sdk.SlicerNode, error) {
+	if f.MockGetHostGroupNodes == nil {
+		return nil, nil
+	}
+	return f.MockGetHostGroupNodes(ctx, groupName)
+}
+
+func (f *fakeSlicer) CreateNode(ctx context.Context, groupName string, request sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+	if f.MockCreateNode == nil {
+		return &sdk.SlicerCreateNodeResponse{}, nil
+	}
+	return f.MockCreateNode(ctx, groupName, request)
+}
+
+func (f *fakeSlicer) DeleteVM(ctx context.Context, groupName, hostname string) (*sdk.SlicerDeleteResponse, error) {
+	if f.MockDeleteVM == nil {
+		return &sdk.SlicerDeleteResponse{}, nil
+	}
+	return f.MockDeleteVM(ctx, groupName, hostname)
+}
+
+func (f *fakeSlicer) GetVMStats(ctx context.Context, hostname string) ([]sdk.SlicerNodeStat, error) {
+	if f.MockGetVMStats == nil {
+		return nil, nil
+	}
+	return f.MockGetVMStats(ctx, hostname)
+}
+
+func (f *fakeSlicer) Exec(ctx context.Context, nodeName string, execReq sdk.SlicerExecRequest) (chan sdk.SlicerExecWriteResult, error) {
+	if f.MockExec == nil {
+		ch := make(chan sdk.SlicerExecWriteResult)
+		close(ch)
+		return ch, nil
+	}
+	return f.MockExec(ctx, nodeName, execReq)
+}
+
+const (
+	testHostGroup = "api"
+	testHostname  = "api-1"
+	testIP        = "192.168.137.2/24"
+	testUID       = "0a1b2c3d"
+)
+
+var (
+	errBoom        = errors.New("boom")
+	errRateLimited = &apiError{StatusCode: http.StatusTooManyRequests, Body: "slow down"}
+	errNotFound    = &apiError{StatusCode: http.StatusNotFound, Body: "not found"}
+)
+
+type vmModifier func(*v1alpha1.VM)
+
+func withExternalName(n string) vmModifier {
+	return func(cr *v1alpha1.VM) { meta.SetExternalName(cr, n) }
+}
+
+func withDeletionTimestamp() vmModifier {
+	return func(cr *v1alpha1.VM) {
+		now := metav1.Now()
+		cr.SetDeletionTimestamp(&now)
+	}
+}
+
+func withSize(cpus, ramGB int) vmModifier {
+	return func(cr *v1alpha1.VM) {
+		cr.Spec.ForProvider.CPUs = cpus
+		cr.Spec.ForProvider.RAMGB = ramGB
+	}
+}
+
+func withObservedHostGroup(hg string) vmModifier {
+	return func(cr *v1alpha1.VM) { cr.Status.AtProvider.HostGroup = hg }
+}
+
+func withAnnotation(k, v string) vmModifier {
+	return func(cr *v1alpha1.VM) { meta.AddAnnotations(cr, map[string]string{k: v}) }
+}
+
+func withConditions(c ...xpv1.Condition) vmModifier {
+	return func(cr *v1alpha1.VM) { cr.SetConditions(c...) }
+}
+
+func vm(m ...vmModifier) *v1alpha1.VM {
+	cr := &v1alpha1.VM{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "vm", UID: testUID}}
+	for _, fn := range m {
+		fn(cr)
+	}
+	return cr
+}
+
+// node returns a Slicer VM owned by the VM returned by vm.
+func node() sdk.SlicerNode {
+	return sdk.SlicerNode{Hostname: testHostname, IP: testIP, Tags: []string{tagOwnerPrefix + testUID}}
+}
+
+// stats returns stats reporting the supplied size for the VM returned by node.
+func stats(cpus, ramGB int) []sdk.SlicerNodeStat {
+	return []sdk.SlicerNodeStat{{
+		Hostname: testHostname,
+		Snapshot: &sdk.SlicerSnapshot{TotalCPUS: cpus, TotalMemory: uint64(ramGB) << 30},
+	}}
+}
+
+func listNodes(nodes []sdk.SlicerNode, err error) func(context.Context, string) ([]sdk.SlicerNode, error) {
+	return func(context.Context, string) ([]sdk.SlicerNode, error) { return nodes, err }
+}
+
+// newTestExternal returns an external using the supplied Slicer API and
+// Kubernetes client, configured with the provider's defaults.
+func newTestExternal(api slicerAPI, kube client.Client) *external {
+	if kube == nil {
+		kube = &test.MockClient{MockList: test.NewMockListFn(nil)}
+	}
+	return &external{
+		kube:             kube,
+		reader:           kube,
+		logger:           logging.NewNopLogger(),
+		recorder:         event.NewNopRecorder(),
+		client:           api,
+		apiErrors:        &errorRecorder{},
+		transport:        http.DefaultTransport.(*http.Transport), //nolint:forcetypeassert // The default transport is an *http.Transport.
+		hostGroup:        testHostGroup,
+		defaultCPUs:      defaultCPUs,
+		defaultRAMGB:     defaultRAMGB,
+		createTimeout:    defaultCreateTimeout,
+		deleteTimeout:    defaultDeleteTimeout,
+		observeTimeout:   defaultObserveTimeout,
+		updateTimeout:    defaultUpdateTimeout,
+		shutdownTimeout:  defaultShutdownTimeout,
+		cloudInitTimeout: defaultCloudInitTimeout,
+		restartWindow:    defaultRestartWindow,
+	}
+}
+
+func TestObserve(t *testing.T) {
+	type fields struct {
+		api slicerAPI
+	}
+
+	type args struct {
+		mg resource.Managed
+	}
+
+	type want struct {
+		exists   bool
+		upToDate bool
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		fields fields
+		args   args
+		want   want
+	}{
+		"NotVM": {
+			reason: "We should return an error if the managed resource is not a VM.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: nil},
+			want:   want{err: errors.New(errNotVM)},
+		},
+		"NoExternalName": {
+			reason: "A VM without an external name has not been created yet.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: vm()},
+			want:   want{exists: false},
+		},
+		"VMNotFound": {
+			reason: "A VM that is not listed in its host group does not exist.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: "api-2"}}, nil),
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+			want: want{exists: false},
+		},
+		"HostGroupNotFound": {
+			reason: "A missing host group is a configuration error, not a missing VM.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(nil, errNotFound),
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+			want: want{err: errors.Errorf(errHostGroupNotFound, testHostGroup)},
+		},
+		"HostGroupNotFoundWhileDeleted": {
+			reason: "A VM being deleted whose host group is gone was deleted along with it.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(nil, errNotFound),
+			}},
+			args: args{mg: vm(withExternalName(testHostname), withDeletionTimestamp())},
+			want: want{exists: false},
+		},
+		"ForeignOwner": {
+			reason: "We should refuse to adopt a VM owned by another resource.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: testHostname, Tags: []string{tagOwnerPrefix + "other"}}}, nil),
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+			want: want{err: errors.Errorf(errForeignVM, testHostname)},
+		},
+		"ForeignOwnerWhileDeleted": {
+			reason: "A VM owned by another resource should be released, not deleted.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: testHostname, Tags: []string{tagOwnerPrefix + "other"}}}, nil),
+			}},
+			args: args{mg: vm(withExternalName(testHostname), withDeletionTimestamp())},
+			want: want{exists: false},
+		},
+		"RateLimited": {
+			reason: "We should return an error if the Slicer API rate limits the listing.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(nil, errRateLimited),
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+			want: want{err: errors.Wrap(errRateLimited, "cannot list VMs")},
+		},
+		"Timeout": {
+			reason: "We should return an error if listing VMs times out.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(nil, context.DeadlineExceeded),
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+			want: want{err: errors.Wrap(context.DeadlineExceeded, "cannot list VMs")},
+		},
+		"SizeDrift": {
+			reason: "A VM whose observed size differs from its requested size is not up to date.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{node()}, nil),
+				MockGetVMStats: func(context.Context, string) ([]sdk.SlicerNodeStat, error) {
+					return stats(4, 4), nil
+				},
+			}},
+			args: args{mg: vm(withExternalName(testHostname), withSize(2, 4))},
+			want: want{exists: true, upToDate: false},
+		},
+		"UpToDate": {
+			reason: "A running VM of the requested size is up to date.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{node()}, nil),
+				MockGetVMStats: func(context.Context, string) ([]sdk.SlicerNodeStat, error) {
+					return stats(2, 4), nil
+				},
+			}},
+			args: args{mg: vm(withExternalName(testHostname), withSize(2, 4))},
+			want: want{exists: true, upToDate: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(tc.fields.api, nil)
+			got, err := e.Observe(context.Background(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.exists, got.ResourceExists); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want exists, +got exists:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.upToDate, got.ResourceUpToDate); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want up to date, +got up to date:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveConditions(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		api    slicerAPI
+		mg     *v1alpha1.VM
+		want   []xpv1.Condition
+	}{
+		"ForeignOwner": {
+			reason: "A VM owned by another resource should be marked foreign.",
+			api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: testHostname, Tags: []string{tagOwnerPrefix + "other"}}}, nil),
+			},
+			mg:   vm(withExternalName(testHostname)),
+			want: []xpv1.Condition{v1alpha1.ForeignResource()},
+		},
+		"SizeDrift": {
+			reason: "A VM whose observed size differs from its requested size must be recreated.",
+			api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{node()}, nil),
+				MockGetVMStats: func(context.Context, string) ([]sdk.SlicerNodeStat, error) {
+					return stats(4, 4), nil
+				},
+			},
+			mg: vm(withExternalName(testHostname), withSize(2, 4)),
+			want: []xpv1.Condition{
+				v1alpha1.RecreateRequired().WithMessage("VM has 4 CPUs but 2 are requested; the VM must be recreated for its size to change"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(tc.api, nil)
+			_, _ = e.Observe(context.Background(), tc.mg)
+			for _, want := range tc.want {
+				got := tc.mg.GetCondition(want.Type)
+				if diff := cmp.Diff(want, got, test.EquateConditions(), cmp.FilterPath(func(p cmp.Path) bool {
+					return p.Last().String() == ".LastTransitionTime"
+				}, cmp.Ignore())); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want condition, +got condition:\n%s\n", tc.reason, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	type fields struct {
+		api slicerAPI
+	}
+
+	type args struct {
+		mg resource.Managed
+	}
+
+	type want struct {
+		externalName string
+		attempts     int
+		err          error
+	}
+
+	cases := map[string]struct {
+		reason string
+		fields fields
+		args   args
+		want   want
+	}{
+		"NotVM": {
+			reason: "We should return an error if the managed resource is not a VM.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: nil},
+			want:   want{err: errors.New(errNotVM)},
+		},
+		"Created": {
+			reason: "We should set the external name of a created VM to its hostname.",
+			fields: fields{api: &fakeSlicer{
+				MockCreateNode: func(_ context.Context, hg string, req sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					if hg != testHostGroup || req.CPUs != 2 || req.RamGB != 4 {
+						return nil, errBoom
+					}
+					return &sdk.SlicerCreateNodeResponse{Hostname: testHostname, IP: testIP}, nil
+				},
+			}},
+			args: args{mg: vm(withSize(2, 4))},
+			want: want{externalName: testHostname},
+		},
+		"CreatedEarlier": {
+			reason: "We should not create a VM if one created by an earlier attempt exists.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{node()}, nil),
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, errBoom
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{externalName: testHostname},
+		},
+		"ForeignOwner": {
+			reason: "A VM owned by another resource was not created by an earlier attempt.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes([]sdk.SlicerNode{{Hostname: testHostname, Tags: []string{tagOwnerPrefix + "other"}}}, nil),
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return &sdk.SlicerCreateNodeResponse{Hostname: "api-2"}, nil
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{externalName: "api-2"},
+		},
+		"ExceedsHostSize": {
+			reason: "A VM that does not fit on a host should never be created.",
+			fields: fields{api: &fakeSlicer{
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, errBoom
+				},
+			}},
+			args: args{mg: vm(withSize(64, 4))},
+			want: want{attempts: 1, err: errors.Errorf(errExceedsMaxCPUs, 64, 32)},
+		},
+		"HostGroupNotFound": {
+			reason: "A missing host group should be reported as such, and count as a failed attempt.",
+			fields: fields{api: &fakeSlicer{
+				MockGetHostGroupNodes: listNodes(nil, errNotFound),
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, errNotFound
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{attempts: 1, err: errors.Errorf(errHostGroupNotFound, testHostGroup)},
+		},
+		"RateLimited": {
+			reason: "Being rate limited should not count as a failed attempt.",
+			fields: fields{api: &fakeSlicer{
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, errRateLimited
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{err: errors.Wrap(errRateLimited, "cannot create VM")},
+		},
+		"Timeout": {
+			reason: "A timed out create should be retried, and not count as a failed attempt.",
+			fields: fields{api: &fakeSlicer{
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, context.DeadlineExceeded
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{err: errors.Wrapf(context.DeadlineExceeded, errCreateTimeout, defaultCreateTimeout)},
+		},
+		"CreateFailed": {
+			reason: "Other errors should count as failed attempts.",
+			fields: fields{api: &fakeSlicer{
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					return nil, errBoom
+				},
+			}},
+			args: args{mg: vm()},
+			want: want{attempts: 1, err: errors.Wrap(errBoom, "cannot create VM")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(tc.fields.api, nil)
+			e.maxCPUs = 32
+			_, err := e.Create(context.Background(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			cr, ok := tc.args.mg.(*v1alpha1.VM)
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(tc.want.externalName, meta.GetExternalName(cr)); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want external name, +got external name:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.attempts, cr.Status.AtProvider.CreateAttempts); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want create attempts, +got create attempts:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	type fields struct {
+		api          slicerAPI
+		updatePolicy apisv1alpha1.UpdatePolicy
+	}
+
+	type args struct {
+		mg resource.Managed
+	}
+
+	type want struct {
+		err error
+	}
+
+	// changed returns a VM created with different parameters than it has.
+	changed := func(m ...vmModifier) *v1alpha1.VM {
+		cr := vm(append([]vmModifier{withExternalName(testHostname), withObservedHostGroup(testHostGroup)}, m...)...)
+		cr.Status.AtProvider.CreateParametersHash = "old"
+		return cr
+	}
+	sizeDrift := "VM has 4 CPUs but 2 are requested; the VM must be recreated for its size to change"
+
+	cases := map[string]struct {
+		reason string
+		fields fields
+		args   args
+		want   want
+	}{
+		"NotVM": {
+			reason: "We should return an error if the managed resource is not a VM.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: nil},
+			want:   want{err: errors.New(errNotVM)},
+		},
+		"NothingToDo": {
+			reason: "A VM whose create parameters have not changed needs no update.",
+			fields: fields{api: &fakeSlicer{
+				MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+					return nil, errBoom
+				},
+			}},
+			args: args{mg: vm(withExternalName(testHostname))},
+		},
+		"Reboot": {
+			reason: "We should reboot a VM whose reboot annotation changed.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: vm(withExternalName(testHostname), withAnnotation(annotationReboot, "1"))},
+		},
+		"RebootFailed": {
+			reason: "We should return an error if a VM cannot be rebooted.",
+			fields: fields{api: &fakeSlicer{
+				MockExec: func(context.Context, string, sdk.SlicerExecRequest) (chan sdk.SlicerExecWriteResult, error) {
+					return nil, errBoom
+				},
+			}},
+			args: args{mg: vm(withExternalName(testHostname), withAnnotation(annotationReboot, "1"))},
+			want: want{err: errors.Wrap(errBoom, errRebootVM)},
+		},
+		"SizeDrift": {
+			reason: "A VM whose size drifted although its parameters did not change should never be recreated automatically.",
+			fields: fields{
+				api: &fakeSlicer{
+					MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+						return nil, errBoom
+					},
+				},
+				updatePolicy: apisv1alpha1.UpdatePolicyRecreate,
+			},
+			args: args{mg: vm(withExternalName(testHostname), withConditions(v1alpha1.RecreateRequired().WithMessage(sizeDrift)))},
+			want: want{err: errors.New(sizeDrift)},
+		},
+		"RecreateNotAllowed": {
+			reason: "Changed create parameters should be reported unless VMs may be recreated.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: changed()},
+			want:   want{err: errors.New(errRecreateRequired)},
+		},
+		"Recreate": {
+			reason: "A VM whose create parameters changed should be deleted for recreation.",
+			fields: fields{
+				api: &fakeSlicer{
+					MockDeleteVM: func(_ context.Context, hg, hostname string) (*sdk.SlicerDeleteResponse, error) {
+						if hg != testHostGroup || hostname != testHostname {
+							return nil, errBoom
+						}
+						return &sdk.SlicerDeleteResponse{}, nil
+					},
+				},
+				updatePolicy: apisv1alpha1.UpdatePolicyRecreate,
+			},
+			args: args{mg: changed()},
+		},
+		"RecreateHostGroupNotFound": {
+			reason: "A VM whose host group is gone is already deleted.",
+			fields: fields{
+				api: &fakeSlicer{
+					MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+						return nil, errNotFound
+					},
+				},
+				updatePolicy: apisv1alpha1.UpdatePolicyRecreate,
+			},
+			args: args{mg: changed()},
+		},
+		"RecreateRateLimited": {
+			reason: "We should return an error if deleting the VM is rate limited.",
+			fields: fields{
+				api: &fakeSlicer{
+					MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+						return nil, errRateLimited
+					},
+				},
+				updatePolicy: apisv1alpha1.UpdatePolicyRecreate,
+			},
+			args: args{mg: changed()},
+			want: want{err: errors.Wrap(errRateLimited, errRecreateVM)},
+		},
+		"RecreateTimeout": {
+			reason: "We should return an error if deleting the VM times out.",
+			fields: fields{
+				api: &fakeSlicer{
+					MockDeleteVM: func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+						return nil, context.DeadlineExceeded
+					},
+				},
+				updatePolicy: apisv1alpha1.UpdatePolicyRecreate,
+			},
+			args: args{mg: changed()},
+			want: want{err: errors.Wrap(context.DeadlineExceeded, errRecreateVM)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(tc.fields.api, nil)
+			e.updatePolicy = tc.fields.updatePolicy
+			_, err := e.Update(context.Background(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Update(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type fields struct {
+		api slicerAPI
+	}
+
+	type args struct {
+		mg resource.Managed
+	}
+
+	type want struct {
+		err error
+	}
+
+	// deleteVM expects the supplied VM of the supplied host group to be
+	// deleted.
+	deleteVM := func(wantHostGroup, wantHostname string, err error) func(context.Context, string, string) (*sdk.SlicerDeleteResponse, error) {
+		return func(_ context.Context, hg, hostname string) (*sdk.SlicerDeleteResponse, error) {
+			if hg != wantHostGroup || hostname != wantHostname {
+				return nil, errors.Errorf("deleted %s/%s, want %s/%s", hg, hostname, wantHostGroup, wantHostname)
+			}
+			return &sdk.SlicerDeleteResponse{}, err
+		}
+	}
+
+	cases := map[string]struct {
+		reason string
+		fields fields
+		args   args
+		want   want
+	}{
+		"NotVM": {
+			reason: "We should return an error if the managed resource is not a VM.",
+			fields: fields{api: &fakeSlicer{}},
+			args:   args{mg: nil},
+			want:   want{err: errors.New(errNotVM)},
+		},
+		"NoExternalName": {
+			reason: "A VM that was never created has nothing to delete.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM("", "", errBoom)}},
+			args:   args{mg: vm()},
+		},
+		"Deleted": {
+			reason: "We should delete the VM from its host group.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM(testHostGroup, testHostname, nil)}},
+			args:   args{mg: vm(withExternalName(testHostname))},
+		},
+		"ObservedHostGroup": {
+			reason: "We should delete the VM from the host group it was created in.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM("old", testHostname, nil)}},
+			args:   args{mg: vm(withExternalName(testHostname), withObservedHostGroup("old"))},
+		},
+		"VMNotFound": {
+			reason: "A VM that no longer exists is already deleted.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM(testHostGroup, testHostname, errNotFound)}},
+			args:   args{mg: vm(withExternalName(testHostname))},
+		},
+		"RateLimited": {
+			reason: "We should return an error if deleting the VM is rate limited.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM(testHostGroup, testHostname, errRateLimited)}},
+			args:   args{mg: vm(withExternalName(testHostname))},
+			want:   want{err: errors.Wrap(errRateLimited, "cannot delete VM")},
+		},
+		"Timeout": {
+			reason: "We should return an error if deleting the VM times out.",
+			fields: fields{api: &fakeSlicer{MockDeleteVM: deleteVM(testHostGroup, testHostname, context.DeadlineExceeded)}},
+			args:   args{mg: vm(withExternalName(testHostname))},
+			want:   want{err: errors.Wrap(context.DeadlineExceeded, "cannot delete VM")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(tc.fields.api, nil)
+			_, err := e.Delete(context.Background(), tc.args.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Delete(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDedupeSSHKeys(t *testing.T) {
+	const (
+		ed25519 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGb1"
+		rsa     = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC2"
+	)
+
+	cases := map[string]struct {
+		reason string
+		keys   []string
+		want   []string
+	}{
+		"NoKeys": {
+			reason: "No keys should dedupe to none.",
+			keys:   nil,
+			want:   []string{},
+		},
+		"DifferentComments": {
+			reason: "Keys differing only in their comment are duplicates, and the first is kept as is.",
+			keys:   []string{ed25519 + " alice@laptop", ed25519 + " alice@desktop", ed25519},
+			want:   []string{ed25519 + " alice@laptop"},
+		},
+		"DifferentWhitespace": {
+			reason: "Keys differing only in whitespace are duplicates.",
+			keys:   []string{ed25519, "  " + ed25519 + "\t"},
+			want:   []string{ed25519},
+		},
+		"DifferentKeys": {
+			reason: "Different keys should all be kept, in order.",
+			keys:   []string{rsa + " bob", ed25519 + " alice", rsa + " robert"},
+			want:   []string{rsa + " bob", ed25519 + " alice"},
+		},
+		"BlankKeys": {
+			reason: "Blank keys should be dropped.",
+			keys:   []string{"", " ", ed25519},
+			want:   []string{ed25519},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := dedupeSSHKeys(tc.keys)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ndedupeSSHKeys(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCreateDedupesSSHKeys(t *testing.T) {
+	const key = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGb1"
+
+	var got []string
+	e := newTestExternal(&fakeSlicer{
+		MockCreateNode: func(_ context.Context, _ string, req sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+			got = req.SSHKeys
+			return &sdk.SlicerCreateNodeResponse{Hostname: testHostname}, nil
+		},
+	}, nil)
+	cr := vm()
+	cr.Spec.ForProvider.SSHKeys = []string{key + " alice@laptop", key + " alice@desktop"}
+
+	if _, err := e.Create(context.Background(), cr); err != nil {
+		t.Fatalf("e.Create(...): unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{key + " alice@laptop"}, got); diff != "" {
+		t.Errorf("e.Create(...): -want SSH keys, +got SSH keys:\n%s\n", diff)
+	}
+}
+
+func TestResolveUserdata(t *testing.T) {
+	const userdata = "#cloud-config\npackages: [htop]\n"
+
+	// kube serves a secret and a config map named userdata.
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			if key.Namespace != "default" || key.Name != "userdata" {
+				return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+			}
+			switch o := obj.(type) {
+			case *corev1.Secret:
+				o.Data = map[string][]byte{"user-data": []byte(userdata)}
+			case *corev1.ConfigMap:
+				o.Data = map[string]string{"user-data": userdata}
+				o.BinaryData = map[string][]byte{"user-data.bin": []byte(userdata)}
+			}
+			return nil
+		},
+	}
+
+	type want struct {
+		userdata string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		params v1alpha1.VMParameters
+		want   want
+	}{
+		"Inline": {
+			reason: "Inline userdata takes precedence over userdata read from a source.",
+			params: v1alpha1.VMParameters{
+				Userdata:     "inline",
+				UserdataFrom: &v1alpha1.UserdataSource{SecretKeyRef: &xpv1.LocalSecretKeySelector{LocalSecretReference: xpv1.LocalSecretReference{Name: "userdata"}, Key: "user-data"}},
+			},
+			want: want{userdata: "inline"},
+		},
+		"SecretKey": {
+			reason: "Userdata should be read from a secret key.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{SecretKeyRef: &xpv1.LocalSecretKeySelector{LocalSecretReference: xpv1.LocalSecretReference{Name: "userdata"}, Key: "user-data"}},
+			},
+			want: want{userdata: userdata},
+		},
+		"MissingSecretKey": {
+			reason: "A secret lacking the referenced key is an error.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{SecretKeyRef: &xpv1.LocalSecretKeySelector{LocalSecretReference: xpv1.LocalSecretReference{Name: "userdata"}, Key: "missing"}},
+			},
+			want: want{err: errors.Errorf(errMissingUserdataSecretKey, "userdata", "missing")},
+		},
+		"MissingSecret": {
+			reason: "A missing secret is an error.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{SecretKeyRef: &xpv1.LocalSecretKeySelector{LocalSecretReference: xpv1.LocalSecretReference{Name: "missing"}, Key: "user-data"}},
+			},
+			want: want{err: errors.Wrapf(kerrors.NewNotFound(schema.GroupResource{}, "missing"), errGetUserdataSecret, "missing")},
+		},
+		"ConfigMapKey": {
+			reason: "Userdata should be read from a config map key.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{ConfigMapKeyRef: &v1alpha1.ConfigMapKeySelector{Name: "userdata", Key: "user-data"}},
+			},
+			want: want{userdata: userdata},
+		},
+		"ConfigMapBinaryKey": {
+			reason: "Userdata should be read from a binary config map key.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{ConfigMapKeyRef: &v1alpha1.ConfigMapKeySelector{Name: "userdata", Key: "user-data.bin"}},
+			},
+			want: want{userdata: userdata},
+		},
+		"MissingConfigMapKey": {
+			reason: "A config map lacking the referenced key is an error.",
+			params: v1alpha1.VMParameters{
+				UserdataFrom: &v1alpha1.UserdataSource{ConfigMapKeyRef: &v1alpha1.ConfigMapKeySelector{Name: "userdata", Key: "missing"}},
+			},
+			want: want{err: errors.Errorf(errMissingUserdataConfigMapKey, "userdata", "missing")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(&fakeSlicer{}, kube)
+			cr := vm()
+			cr.Spec.ForProvider = tc.params
+			got, err := e.resolveUserdata(context.Background(), cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.resolveUserdata(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.userdata, got); diff != "" {
+				t.Errorf("\n%s\ne.resolveUserdata(...): -want userdata, +got userdata:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestHostGroupNotFound(t *testing.T) {
+	// api serves a host group named api holding another VM. Other host
+	// groups do not exist.
+	api := &fakeSlicer{
+		MockGetHostGroupNodes: func(_ context.Context, hg string) ([]sdk.SlicerNode, error) {
+			if hg != testHostGroup {
+				return nil, errNotFound
+			}
+			return []sdk.SlicerNode{{Hostname: "api-2"}}, nil
+		},
+		MockCreateNode: func(_ context.Context, hg string, _ sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+			if hg != testHostGroup {
+				return nil, errNotFound
+			}
+			return &sdk.SlicerCreateNodeResponse{Hostname: testHostname}, nil
+		},
+	}
+	inHostGroup := func(hg string) vmModifier {
+		return func(cr *v1alpha1.VM) { cr.Spec.ForProvider.HostGroup = hg }
+	}
+
+	type want struct {
+		exists bool
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason    string
+		selection *apisv1alpha1.HostGroupSelection
+		run       func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error)
+		cr        *v1alpha1.VM
+		want      want
+	}{
+		"ObserveVMMissing": {
+			reason: "A VM missing from an existing host group should be reported as not existing, so it is created.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error) {
+				o, err := e.Observe(ctx, cr)
+				return o.ResourceExists, err
+			},
+			cr:   vm(withExternalName(testHostname)),
+			want: want{exists: false},
+		},
+		"ObserveHostGroupMissing": {
+			reason: "A VM of a missing host group should fail with an actionable error rather than be recreated.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error) {
+				o, err := e.Observe(ctx, cr)
+				return o.ResourceExists, err
+			},
+			cr:   vm(withExternalName(testHostname), inHostGroup("missing")),
+			want: want{err: errors.Errorf(errHostGroupNotFound, "missing")},
+		},
+		"CreateHostGroupMissing": {
+			reason: "Creating a VM in a missing host group should fail with an actionable error.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error) {
+				_, err := e.Create(ctx, cr)
+				return meta.GetExternalName(cr) != "", err
+			},
+			cr:   vm(inHostGroup("missing")),
+			want: want{err: errors.Errorf(errHostGroupNotFound, "missing")},
+		},
+		"CreateCandidateHostGroupMissing": {
+			reason: "A missing candidate host group should fail with an actionable error naming it.",
+			selection: &apisv1alpha1.HostGroupSelection{
+				HostGroups: []string{testHostGroup, "missing"},
+			},
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error) {
+				_, err := e.Create(ctx, cr)
+				return meta.GetExternalName(cr) != "", err
+			},
+			cr:   vm(),
+			want: want{err: errors.Errorf(errHostGroupNotFound, "missing")},
+		},
+		"CreateVMMissing": {
+			reason: "A VM missing from an existing host group should be created.",
+			run: func(ctx context.Context, e *external, cr *v1alpha1.VM) (bool, error) {
+				_, err := e.Create(ctx, cr)
+				return meta.GetExternalName(cr) != "", err
+			},
+			cr:   vm(),
+			want: want{exists: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(api, nil)
+			e.hostGroupSelection = tc.selection
+			exists, err := tc.run(context.Background(), e, tc.cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\n-want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.exists, exists); diff != "" {
+				t.Errorf("\n%s\n-want exists, +got exists:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectCredentials(t *testing.T) {
+	const token = "s3cr3t"
+
+	dir := t.TempDir()
+	tokenFile, emptyFile := filepath.Join(dir, "token"), filepath.Join(dir, "empty")
+	// Tokens read from files usually end with a newline.
+	if err := os.WriteFile(tokenFile, []byte(token+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(emptyFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SLICER_TOKEN", token)
+	t.Setenv("SLICER_EMPTY_TOKEN", "")
+
+	secretRef := func(key string) *xpv1.SecretKeySelector {
+		return &xpv1.SecretKeySelector{SecretReference: xpv1.SecretReference{Namespace: "crossplane-system", Name: "slicer"}, Key: key}
+	}
+
+	type want struct {
+		token string
+		err   error
+	}
+
+	cases := map[string]struct {
+		reason string
+		creds  apisv1alpha1.ProviderCredentials
+		want   want
+	}{
+		"Secret": {
+			reason: "The token should be read from a secret.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{SecretRef: secretRef("token")},
+			},
+			want: want{token: token},
+		},
+		"EmptySecret": {
+			reason: "An empty token read from a secret should be rejected.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{SecretRef: secretRef("empty")},
+			},
+			want: want{err: errors.Errorf(errEmptyToken, xpv1.CredentialsSourceSecret)},
+		},
+		"Environment": {
+			reason: "The token should be read from an environment variable.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceEnvironment,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{Env: &xpv1.EnvSelector{Name: "SLICER_TOKEN"}},
+			},
+			want: want{token: token},
+		},
+		"EmptyEnvironment": {
+			reason: "An empty token read from an environment variable should be rejected.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceEnvironment,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{Env: &xpv1.EnvSelector{Name: "SLICER_EMPTY_TOKEN"}},
+			},
+			want: want{err: errors.Errorf(errEmptyToken, xpv1.CredentialsSourceEnvironment)},
+		},
+		"Filesystem": {
+			reason: "The token should be read from a file, without its trailing newline.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceFilesystem,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{Fs: &xpv1.FsSelector{Path: tokenFile}},
+			},
+			want: want{token: token},
+		},
+		"EmptyFilesystem": {
+			reason: "An empty token read from a file should be rejected.",
+			creds: apisv1alpha1.ProviderCredentials{
+				Source:                    xpv1.CredentialsSourceFilesystem,
+				CommonCredentialSelectors: xpv1.CommonCredentialSelectors{Fs: &xpv1.FsSelector{Path: emptyFile}},
+			},
+			want: want{err: errors.Errorf(errEmptyToken, xpv1.CredentialsSourceFilesystem)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					switch o := obj.(type) {
+					case *apisv1alpha1.ProviderConfig:
+						o.Spec.Credentials = tc.creds
+						return nil
+					case *corev1.Secret:
+						o.Data = map[string][]byte{"token": []byte(token), "empty": nil}
+						return nil
+					}
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				},
+				MockCreate: test.NewMockCreateFn(nil),
+			}
+			c := &connector{
+				kube:     kube,
+				usage:    resource.NewProviderConfigUsageTracker(kube, &apisv1alpha1.ProviderConfigUsage{}),
+				features: &feature.Flags{},
+				logger:   logging.NewNopLogger(),
+				throttle: newThrottles(),
+				creates:  newCreateSlots(),
+			}
+			cr := vm()
+			cr.SetProviderConfigReference(&xpv1.ProviderConfigReference{Kind: "ProviderConfig", Name: "default"})
+
+			got, err := c.Connect(context.Background(), cr)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if tc.want.token == "" {
+				return
+			}
+			e, _ := got.(*external)
+			if diff := cmp.Diff(apiID("http://127.0.0.1:8080", tc.want.token), e.apiID); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): the client does not use the expected token:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// A create that times out after the VM was created in the least loaded host
+// group makes that host group busier. Retrying the create must find the VM
+// rather than creating a second one in another host group.
+func TestCreateRetryAfterTimeout(t *testing.T) {
+	type want struct {
+		creates   int
+		hostGroup string
+	}
+
+	cases := map[string]struct {
+		reason string
+		before func(cr *v1alpha1.VM)
+		want   want
+	}{
+		"Retried": {
+			reason: "A retried create should find the VM in the host group recorded before the create.",
+			before: func(*v1alpha1.VM) {},
+			want:   want{creates: 1, hostGroup: "a"},
+		},
+		"StatusLost": {
+			reason: "A retried create should find the VM in any candidate host group if the recorded host group was lost.",
+			before: func(cr *v1alpha1.VM) { cr.Status.AtProvider.HostGroup = "" },
+			want:   want{creates: 1, hostGroup: "a"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			groups := map[string][]sdk.SlicerNode{"a": nil, "b": nil}
+			creates := 0
+			api := &fakeSlicer{
+				MockGetHostGroupNodes: func(_ context.Context, hg string) ([]sdk.SlicerNode, error) {
+					return append([]sdk.SlicerNode{}, groups[hg]...), nil
+				},
+				MockCreateNode: func(_ context.Context, hg string, req sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					creates++
+					groups[hg] = append(groups[hg], sdk.SlicerNode{Hostname: fmt.Sprintf("%s-%d", hg, creates), Tags: req.Tags})
+					return nil, context.DeadlineExceeded
+				},
+			}
+			e := newTestExternal(api, nil)
+			e.hostGroupSelection = &apisv1alpha1.HostGroupSelection{HostGroups: []string{"a", "b"}}
+			cr := vm()
+
+			if _, err := e.Create(context.Background(), cr); !isTimeout(err) {
+				t.Fatalf("\n%s\ne.Create(...): want timeout, got %v", tc.reason, err)
+			}
+			tc.before(cr)
+			if _, err := e.Create(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Create(...): unexpected error: %v", tc.reason, err)
+			}
+			got := want{creates: creates, hostGroup: cr.Status.AtProvider.HostGroup}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff("a-1", meta.GetExternalName(cr)); diff != "" {
+				t.Errorf("\n%s\ne.Create(...): -want external name, +got external name:\n%s\n", tc.reason, diff)
+			}
+
+			// Observing the VM must find it even if its host group is not
+			// recorded.
+			tc.before(cr)
+			obs, err := e.Observe(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): unexpected error: %v", tc.reason, err)
+			}
+			if !obs.ResourceExists {
+				t.Errorf("\n%s\ne.Observe(...): created VM was not found", tc.reason)
+			}
+		})
+	}
+}
+
+func TestRAMDrift(t *testing.T) {
+	// MemTotal in kB as reported by guests of VMs of various sizes, which
+	// is below the VM's memory by what its kernel reserves.
+	const (
+		memTotal1GB   = 857_000
+		memTotal4GB   = 4_007_048
+		memTotal32GB  = 32_812_316
+		memTotal256GB = 263_736_000
+	)
+
+	type want struct {
+		drift bool
+		ramGB int
+	}
+
+	cases := map[string]struct {
+		reason   string
+		ramGB    int
+		memTotal int64
+		want     want
+	}{
+		"1GB": {
+			reason:   "A small VM whose kernel reserves a large share of its memory should match its size.",
+			ramGB:    1,
+			memTotal: memTotal1GB,
+			want:     want{ramGB: 1},
+		},
+		"4GB": {
+			reason:   "A VM should match its size.",
+			ramGB:    4,
+			memTotal: memTotal4GB,
+			want:     want{ramGB: 4},
+		},
+		"32GB": {
+			reason:   "A VM reporting about 31.3 GiB should match a size of 32 GB.",
+			ramGB:    32,
+			memTotal: memTotal32GB,
+			want:     want{ramGB: 32},
+		},
+		"256GB": {
+			reason:   "A large VM whose kernel reserves several GB should match its size.",
+			ramGB:    256,
+			memTotal: memTotal256GB,
+			want:     want{ramGB: 252},
+		},
+		"Grown": {
+			reason:   "Requesting 1 GB more than a 32 GB VM has should be reported as drift.",
+			ramGB:    33,
+			memTotal: memTotal32GB,
+			want:     want{drift: true, ramGB: 32},
+		},
+		"Shrunk": {
+			reason:   "Requesting less than a VM has should be reported as drift.",
+			ramGB:    3,
+			memTotal: memTotal4GB,
+			want:     want{drift: true, ramGB: 4},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestExternal(&fakeSlicer{}, nil)
+			cr := vm(withSize(2, tc.ramGB))
+			cr.Status.AtProvider.MemoryBytes = ptr.To(tc.memTotal * 1024)
+
+			got := want{drift: e.sizeDrift(cr) != "", ramGB: ramGBOf(*cr.Status.AtProvider.MemoryBytes)}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ne.sizeDrift(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			// The size an adopted VM is late initialized with must match.
+			adopted := vm(withSize(2, 0))
+			adopted.Status.AtProvider.MemoryBytes = cr.Status.AtProvider.MemoryBytes
+			adopted.Status.AtProvider.CPUs = 2
+			e.lateInitialize(adopted, &sdk.SlicerNode{})
+			if drift := e.sizeDrift(adopted); drift != "" {
+				t.Errorf("\n%s\ne.lateInitialize(...): late initialized size drifts: %s", tc.reason, drift)
+			}
+		})
+	}
+}
+
+func TestCreationGracePeriod(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		gracePeriod time.Duration
+		want        bool
+	}{
+		"WithinGracePeriod": {
+			reason:      "A VM created moments ago that is not listed yet should not be created again.",
+			gracePeriod: 30 * time.Second,
+			want:        false,
+		},
+		"Disabled": {
+			reason:      "A VM that is not listed should be created again right away if the grace period is zero.",
+			gracePeriod: 0,
+			want:        true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := vm(withExternalName(testHostname))
+			meta.SetExternalCreateSucceeded(cr, time.Now())
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+					if o, ok := obj.(*v1alpha1.VM); ok {
+						cr.DeepCopyInto(o)
+					}
+					return nil
+				},
+				MockList:         test.NewMockListFn(nil),
+				MockUpdate:       test.NewMockUpdateFn(nil),
+				MockPatch:        test.NewMockPatchFn(nil),
+				MockStatusUpdate: test.NewMockSubResourceUpdateFn(nil),
+			}
+
+			created := false
+			api := &fakeSlicer{
+				MockCreateNode: func(context.Context, string, sdk.SlicerCreateNodeRequest) (*sdk.SlicerCreateNodeResponse, error) {
+					created = true
+					return &sdk.SlicerCreateNodeResponse{Hostname: testHostname, IP: testIP}, nil
+				},
+			}
+			e := newTestExternal(api, kube)
+
+			s := runtime.NewScheme()
+			if err := v1alpha1.SchemeBuilder.AddToScheme(s); err != nil {
+				t.Fatal(err)
+			}
+			r := managed.NewReconciler(&fake.Manager{Client: kube, Scheme: s}, resource.ManagedKind(v1alpha1.VMGroupVersionKind),
+				managed.WithCreationGracePeriod(tc.gracePeriod),
+				managed.WithExternalConnector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+					return e, nil
+				})),
+				managed.WithFinalizer(resource.FinalizerFns{AddFinalizerFn: func(context.Context, resource.Object) error { return nil }}),
+			)
+
+			if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetName()}}); err != nil {
+				t.Fatalf("\n%s\nr.Reconcile(...): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, created); diff != "" {
+				t.Errorf("\n%s\nr.Reconcile(...): -want created, +got created:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
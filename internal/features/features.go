@@ -0,0 +1,32 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features defines the feature flags specific to provider-slicervm.
+package features
+
+import "github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+
+// Feature flags.
+const (
+	// EnableAlphaGuestAgentReadiness only reports a VM as Available once its
+	// guest agent has reported in.
+	EnableAlphaGuestAgentReadiness feature.Flag = "EnableAlphaGuestAgentReadiness"
+
+	// EnableAlphaReachabilityProbe probes whether each VM accepts TCP
+	// connections on its SSH port, and only reports reachable VMs as
+	// Available.
+	EnableAlphaReachabilityProbe feature.Flag = "EnableAlphaReachabilityProbe"
+)
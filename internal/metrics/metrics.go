@@ -0,0 +1,151 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exposes Slicer specific Prometheus metrics.
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// VM states counted by VMStates.
+const (
+	StateAvailable   = "available"
+	StateCreating    = "creating"
+	StateUnavailable = "unavailable"
+	StateDrifted     = "drifted"
+)
+
+var states = []string{StateAvailable, StateCreating, StateUnavailable, StateDrifted}
+
+// VMStates is a Prometheus collector that counts the VMs observed by the
+// provider by state. A nil *VMStates records nothing.
+type VMStates struct {
+	mu     sync.Mutex
+	states map[types.NamespacedName]string
+	desc   *prometheus.Desc
+}
+
+// NewVMStates returns a new VMStates collector.
+func NewVMStates() *VMStates {
+	return &VMStates{
+		states: map[types.NamespacedName]string{},
+		desc: prometheus.NewDesc(
+			"slicervm_vms",
+			"The number of VMs observed by the provider, by state.",
+			[]string{"state"}, nil,
+		),
+	}
+}
+
+// Set records the state the supplied VM was last observed in.
+func (s *VMStates) Set(vm types.NamespacedName, state string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[vm] = state
+}
+
+// Delete stops counting the supplied VM.
+func (s *VMStates) Delete(vm types.NamespacedName) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, vm)
+}
+
+// Describe implements prometheus.Collector.
+func (s *VMStates) Describe(ch chan<- *prometheus.Desc) {
+	ch <- s.desc
+}
+
+// Collect implements prometheus.Collector.
+func (s *VMStates) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	counts := make(map[string]int, len(states))
+	for _, state := range s.states {
+		counts[state]++
+	}
+	s.mu.Unlock()
+
+	for _, state := range states {
+		ch <- prometheus.MustNewConstMetric(s.desc, prometheus.GaugeValue, float64(counts[state]), state)
+	}
+}
+
+// Operations of the Slicer API timed by APICalls.
+const (
+	OperationListNodes  = "GetHostGroupNodes"
+	OperationCreateNode = "CreateNode"
+	OperationDeleteVM   = "DeleteVM"
+	OperationGetVMStats = "GetVMStats"
+	OperationExec       = "Exec"
+)
+
+// Outcomes of the Slicer API calls timed by APICalls.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// APICalls is a Prometheus collector that times the Slicer API calls made by
+// the provider, by operation, host group and outcome. A nil *APICalls records
+// nothing.
+type APICalls struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewAPICalls returns a new APICalls collector.
+func NewAPICalls() *APICalls {
+	return &APICalls{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "slicervm_api_call_duration_seconds",
+			Help:    "The duration of Slicer API calls, by operation, host group and outcome.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation", "host_group", "outcome"}),
+	}
+}
+
+// Observe records a call of the supplied operation against the supplied host
+// group that started at the supplied time and returned the supplied error.
+func (c *APICalls) Observe(operation, hostGroup string, start time.Time, err error) {
+	if c == nil {
+		return
+	}
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	c.duration.WithLabelValues(operation, hostGroup, outcome).Observe(time.Since(start).Seconds())
+}
+
+// Describe implements prometheus.Collector.
+func (c *APICalls) Describe(ch chan<- *prometheus.Desc) {
+	c.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *APICalls) Collect(ch chan<- prometheus.Metric) {
+	c.duration.Collect(ch)
+}
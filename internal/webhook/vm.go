@@ -0,0 +1,218 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook implements admission webhooks for Slicer resources.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+const (
+	errNotVM  = "object is not a VM custom resource"
+	errGetPC  = "cannot get ProviderConfig"
+	errGetCPC = "cannot get ClusterProviderConfig"
+)
+
+// VMDefaults are organization defaults injected into new VMs that don't set
+// the corresponding fields.
+type VMDefaults struct {
+	HostGroup string
+	Tags      []string
+	SSHKeys   []string
+}
+
+// VMLimits bound the size a VM may request. Zero maximums mean no limit.
+type VMLimits struct {
+	MinCPUs  int
+	MaxCPUs  int
+	MinRAMGB int
+	MaxRAMGB int
+}
+
+// SetupVM registers the VM admission webhooks with the supplied manager.
+func SetupVM(mgr ctrl.Manager, d VMDefaults, l VMLimits) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&v1alpha1.VM{}).
+		WithDefaulter(&vmDefaulter{kube: mgr.GetAPIReader(), defaults: d}).
+		WithValidator(&vmValidator{limits: l}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vm-slicervm-crossplane-io-v1alpha1-vm,mutating=true,failurePolicy=fail,sideEffects=None,groups=vm.slicervm.crossplane.io,resources=vms,verbs=create,versions=v1alpha1,name=vms.vm.slicervm.crossplane.io,admissionReviewVersions=v1
+
+// vmDefaulter injects organization defaults into new VMs.
+type vmDefaulter struct {
+	kube     client.Reader
+	defaults VMDefaults
+}
+
+// Default sets the fields of the supplied VM that are unset to their
+// organization defaults. The default host group is not set for VMs whose
+// provider config selects host groups, so that one is selected for them.
+func (d *vmDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cr, ok := obj.(*v1alpha1.VM)
+	if !ok {
+		return errors.New(errNotVM)
+	}
+
+	p := &cr.Spec.ForProvider
+	if p.HostGroup == "" && d.defaults.HostGroup != "" {
+		selects, err := d.selectsHostGroup(ctx, cr)
+		if err != nil {
+			return err
+		}
+		if !selects {
+			p.HostGroup = d.defaults.HostGroup
+		}
+	}
+	if len(p.Tags) == 0 && len(d.defaults.Tags) > 0 {
+		p.Tags = append([]string{}, d.defaults.Tags...)
+	}
+	if len(p.SSHKeys) == 0 && len(d.defaults.SSHKeys) > 0 {
+		p.SSHKeys = append([]string{}, d.defaults.SSHKeys...)
+	}
+	return nil
+}
+
+// selectsHostGroup returns true if the provider config of the supplied VM
+// selects host groups for VMs without one. A provider config that does not
+// exist yet selects none.
+func (d *vmDefaulter) selectsHostGroup(ctx context.Context, cr *v1alpha1.VM) (bool, error) {
+	ref := cr.GetProviderConfigReference()
+	if ref == nil {
+		return false, nil
+	}
+	switch ref.Kind {
+	case "ProviderConfig":
+		pc := &apisv1alpha1.ProviderConfig{}
+		err := d.kube.Get(ctx, client.ObjectKey{Namespace: cr.GetNamespace(), Name: ref.Name}, pc)
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return pc.Spec.HostGroupSelection != nil, errors.Wrap(err, errGetPC)
+	case "ClusterProviderConfig":
+		cpc := &apisv1alpha1.ClusterProviderConfig{}
+		err := d.kube.Get(ctx, client.ObjectKey{Name: ref.Name}, cpc)
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return cpc.Spec.HostGroupSelection != nil, errors.Wrap(err, errGetCPC)
+	}
+	return false, nil
+}
+
+// +kubebuilder:webhook:path=/validate-vm-slicervm-crossplane-io-v1alpha1-vm,mutating=false,failurePolicy=fail,sideEffects=None,groups=vm.slicervm.crossplane.io,resources=vms,verbs=create;update,versions=v1alpha1,name=vms.vm.slicervm.crossplane.io,admissionReviewVersions=v1
+
+// vmValidator rejects VMs that the provider cannot reconcile as intended.
+type vmValidator struct {
+	limits VMLimits
+}
+
+// ValidateCreate validates a new VM.
+func (v *vmValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cr, ok := obj.(*v1alpha1.VM)
+	if !ok {
+		return nil, errors.New(errNotVM)
+	}
+	return nil, v.validate(&v1alpha1.VM{}, cr)
+}
+
+// ValidateUpdate validates an updated VM. Only changed fields are validated,
+// so that VMs admitted before the webhook or its limits changed can still be
+// updated and deleted.
+func (v *vmValidator) ValidateUpdate(_ context.Context, oldObj, obj runtime.Object) (admission.Warnings, error) {
+	old, ok := oldObj.(*v1alpha1.VM)
+	if !ok {
+		return nil, errors.New(errNotVM)
+	}
+	cr, ok := obj.(*v1alpha1.VM)
+	if !ok {
+		return nil, errors.New(errNotVM)
+	}
+	return nil, v.validate(old, cr)
+}
+
+// ValidateDelete allows all VMs to be deleted.
+func (v *vmValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns an error listing everything wrong with the fields of the
+// supplied VM that differ from the supplied old VM.
+func (v *vmValidator) validate(old, cr *v1alpha1.VM) error {
+	var errs field.ErrorList
+	if pi, ok := cr.GetAnnotations()[v1alpha1.AnnotationPollInterval]; ok && pi != old.GetAnnotations()[v1alpha1.AnnotationPollInterval] {
+		p := field.NewPath("metadata", "annotations").Key(v1alpha1.AnnotationPollInterval)
+		d, err := time.ParseDuration(pi)
+		switch {
+		case err != nil:
+			errs = append(errs, field.Invalid(p, pi, "must be a duration such as 15s or 10m"))
+		case d <= 0:
+			errs = append(errs, field.Invalid(p, pi, "must be positive"))
+		}
+	}
+
+	p, fp := field.NewPath("spec", "forProvider"), cr.Spec.ForProvider
+	// An unset size is defaulted by the provider config.
+	if fp.CPUs != 0 && fp.CPUs != old.Spec.ForProvider.CPUs {
+		errs = append(errs, validateBounds(p.Child("cpus"), fp.CPUs, v.limits.MinCPUs, v.limits.MaxCPUs, "CPUs")...)
+	}
+	if fp.RAMGB != 0 && fp.RAMGB != old.Spec.ForProvider.RAMGB {
+		errs = append(errs, validateBounds(p.Child("ramGb"), fp.RAMGB, v.limits.MinRAMGB, v.limits.MaxRAMGB, "GB")...)
+	}
+	for _, k := range slices.Sorted(maps.Keys(fp.Metadata)) {
+		if _, ok := old.Spec.ForProvider.Metadata[k]; ok {
+			continue
+		}
+		if k == "" || strings.Contains(k, "=") {
+			errs = append(errs, field.Invalid(p.Child("metadata").Key(k), k, "must be non-empty and must not contain '='"))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return kerrors.NewInvalid(v1alpha1.VMGroupVersionKind.GroupKind(), cr.GetName(), errs)
+}
+
+// validateBounds returns an error if the supplied value of the supplied field
+// lies outside the supplied bounds. A zero maximum means no limit.
+func validateBounds(p *field.Path, v, minimum, maximum int, unit string) field.ErrorList {
+	switch {
+	case v < minimum:
+		return field.ErrorList{field.Invalid(p, v, fmt.Sprintf("must be at least %d %s", minimum, unit))}
+	case maximum > 0 && v > maximum:
+		return field.ErrorList{field.Invalid(p, v, fmt.Sprintf("must be at most %d %s", maximum, unit))}
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/v2/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apisv1alpha1 "github.com/gaarutyunov/provider-slicervm/apis/v1alpha1"
+	"github.com/gaarutyunov/provider-slicervm/apis/vm/v1alpha1"
+)
+
+func TestValidateBounds(t *testing.T) {
+	p := field.NewPath("spec", "forProvider", "cpus")
+
+	type args struct {
+		v       int
+		minimum int
+		maximum int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   field.ErrorList
+	}{
+		"BelowMinimum": {
+			reason: "A value one below the minimum should be rejected.",
+			args:   args{v: 0, minimum: 1, maximum: 64},
+			want:   field.ErrorList{field.Invalid(p, 0, "must be at least 1 CPUs")},
+		},
+		"Negative": {
+			reason: "A negative value should be rejected.",
+			args:   args{v: -1, minimum: 1, maximum: 64},
+			want:   field.ErrorList{field.Invalid(p, -1, "must be at least 1 CPUs")},
+		},
+		"AtMinimum": {
+			reason: "The minimum itself should be allowed.",
+			args:   args{v: 1, minimum: 1, maximum: 64},
+		},
+		"AtMaximum": {
+			reason: "The maximum itself should be allowed.",
+			args:   args{v: 64, minimum: 1, maximum: 64},
+		},
+		"AboveMaximum": {
+			reason: "A value one above the maximum should be rejected.",
+			args:   args{v: 65, minimum: 1, maximum: 64},
+			want:   field.ErrorList{field.Invalid(p, 65, "must be at most 64 CPUs")},
+		},
+		"NoMaximum": {
+			reason: "A zero maximum should not limit the value.",
+			args:   args{v: 1 << 20, minimum: 1, maximum: 0},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := validateBounds(p, tc.args.v, tc.args.minimum, tc.args.maximum, "CPUs")
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nvalidateBounds(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	limits := VMLimits{MinCPUs: 1, MaxCPUs: 8, MinRAMGB: 1, MaxRAMGB: 32}
+
+	vm := func(cpus, ramGB int, metadata map[string]string) *v1alpha1.VM {
+		cr := &v1alpha1.VM{}
+		cr.SetName("vm")
+		cr.Spec.ForProvider.CPUs = cpus
+		cr.Spec.ForProvider.RAMGB = ramGB
+		cr.Spec.ForProvider.Metadata = metadata
+		return cr
+	}
+
+	cases := map[string]struct {
+		reason string
+		old    *v1alpha1.VM
+		cr     *v1alpha1.VM
+		want   []string
+	}{
+		"Unset": {
+			reason: "An unset size is defaulted by the provider config, and should be allowed.",
+			old:    vm(0, 0, nil),
+			cr:     vm(0, 0, nil),
+		},
+		"AtBounds": {
+			reason: "Sizes at the bounds should be allowed.",
+			old:    vm(2, 2, nil),
+			cr:     vm(8, 1, nil),
+		},
+		"OutOfBounds": {
+			reason: "Sizes beyond the bounds should be rejected with field scoped errors.",
+			old:    vm(2, 2, nil),
+			cr:     vm(9, 33, nil),
+			want:   []string{"spec.forProvider.cpus", "spec.forProvider.ramGb"},
+		},
+		"UnchangedOutOfBounds": {
+			reason: "VMs admitted before the limits were lowered should still be updatable.",
+			old:    vm(16, 64, nil),
+			cr:     vm(16, 64, nil),
+		},
+		"UnchangedMetadataKey": {
+			reason: "Invalid metadata keys admitted earlier should still be updatable.",
+			old:    vm(0, 0, map[string]string{"a=b": "c"}),
+			cr:     vm(0, 0, map[string]string{"a=b": "d"}),
+		},
+		"NewMetadataKey": {
+			reason: "Invalid new metadata keys should be rejected.",
+			old:    vm(0, 0, nil),
+			cr:     vm(0, 0, map[string]string{"a=b": "c"}),
+			want:   []string{"spec.forProvider.metadata[a=b]"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			v := &vmValidator{limits: limits}
+			_, err := v.ValidateUpdate(context.Background(), tc.old, tc.cr)
+			var got []string
+			var se *kerrors.StatusError
+			if errors.As(err, &se) {
+				for _, c := range se.Status().Details.Causes {
+					got = append(got, c.Field)
+				}
+			} else if err != nil {
+				t.Fatalf("\n%s\nv.ValidateUpdate(...): unexpected error: %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nv.ValidateUpdate(...): -want invalid fields, +got invalid fields:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDefaultHostGroup(t *testing.T) {
+	errBoom := errors.New("boom")
+	selection := &apisv1alpha1.HostGroupSelection{HostGroups: []string{"a", "b"}}
+
+	vm := func(kind, hostGroup string) *v1alpha1.VM {
+		cr := &v1alpha1.VM{}
+		cr.SetNamespace("team")
+		cr.SetName("vm")
+		cr.SetProviderConfigReference(&xpv1.ProviderConfigReference{Kind: kind, Name: "default"})
+		cr.Spec.ForProvider.HostGroup = hostGroup
+		return cr
+	}
+	// get returns a Get function answering with a provider config of the
+	// supplied kind that uses the supplied host group selection.
+	get := func(kind string, s *apisv1alpha1.HostGroupSelection) test.MockGetFn {
+		return func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+			switch o := obj.(type) {
+			case *apisv1alpha1.ProviderConfig:
+				if kind != "ProviderConfig" || key.Namespace != "team" {
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				}
+				o.Spec.HostGroupSelection = s
+			case *apisv1alpha1.ClusterProviderConfig:
+				if kind != "ClusterProviderConfig" {
+					return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+				}
+				o.Spec.HostGroupSelection = s
+			}
+			return nil
+		}
+	}
+
+	type want struct {
+		hostGroup string
+		err       error
+	}
+
+	cases := map[string]struct {
+		reason string
+		get    test.MockGetFn
+		cr     *v1alpha1.VM
+		want   want
+	}{
+		"NoSelection": {
+			reason: "A VM without a host group should get the default host group.",
+			get:    get("ClusterProviderConfig", nil),
+			cr:     vm("ClusterProviderConfig", ""),
+			want:   want{hostGroup: "default"},
+		},
+		"ClusterSelection": {
+			reason: "A VM whose cluster provider config selects host groups should not get the default host group.",
+			get:    get("ClusterProviderConfig", selection),
+			cr:     vm("ClusterProviderConfig", ""),
+		},
+		"NamespacedSelection": {
+			reason: "A VM whose provider config in its namespace selects host groups should not get the default host group.",
+			get:    get("ProviderConfig", selection),
+			cr:     vm("ProviderConfig", ""),
+		},
+		"ProviderConfigMissing": {
+			reason: "A VM whose provider config does not exist yet should get the default host group.",
+			get:    get("", nil),
+			cr:     vm("ProviderConfig", ""),
+			want:   want{hostGroup: "default"},
+		},
+		"HostGroupSet": {
+			reason: "A VM with a host group should keep it.",
+			get:    get("ClusterProviderConfig", nil),
+			cr:     vm("ClusterProviderConfig", "mine"),
+			want:   want{hostGroup: "mine"},
+		},
+		"GetError": {
+			reason: "Errors reading the provider config should be returned.",
+			get:    test.NewMockGetFn(errBoom),
+			cr:     vm("ClusterProviderConfig", ""),
+			want:   want{err: errors.Wrap(errBoom, errGetCPC)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := &vmDefaulter{kube: &test.MockClient{MockGet: tc.get}, defaults: VMDefaults{HostGroup: "default"}}
+			err := d.Default(context.Background(), tc.cr)
+			got := want{hostGroup: tc.cr.Spec.ForProvider.HostGroup, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nd.Default(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}